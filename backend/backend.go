@@ -112,6 +112,13 @@ type Operation struct {
 	PlanOutPath    string // PlanOutPath is the path to save the plan
 	PlanOutBackend *terraform.BackendState
 
+	// PlanRefreshTargeted limits the refresh done by PlanRefresh to the
+	// resources whose configuration has changed since they were last
+	// applied, and the resources they depend on. Any other resources are
+	// planned using what is already in the state, so changes made outside
+	// of Terraform to those resources aren't detected.
+	PlanRefreshTargeted bool
+
 	// Module settings specify the root module to use for operations.
 	Module *module.Tree
 
@@ -71,4 +71,8 @@ type CLIOpts struct {
 	// Validate.
 	Input      bool
 	Validation bool
+
+	// CompactWarnings will list each distinct warning from validation just
+	// once, without the explanation that normally comes before them.
+	CompactWarnings bool
 }
@@ -46,7 +46,9 @@ type Local struct {
 	// If this is empty, it will default to StatePath.
 	//
 	// StateBackupPath is the local path where a backup file will be written.
-	// Set this to "-" to disable state backup.
+	// Set this to "-" to disable state backup. If backups are compressed, as
+	// configured by state.StateBackupCompressEnvVar, then
+	// state.CompressedBackupExtension is added to this path.
 	//
 	// StateWorkspaceDir is the path to the folder containing data for
 	// non-default workspaces. This defaults to DefaultWorkspaceDir if not set.
@@ -55,6 +57,11 @@ type Local struct {
 	StateBackupPath   string
 	StateWorkspaceDir string
 
+	// StateEncryption is used to encrypt the local state files and their
+	// backups. If this is nil, it is configured from the environment using
+	// state.EncryptionFromEnv each time a state is loaded.
+	StateEncryption state.StateEncryption
+
 	// We only want to create a single instance of a local state, so store them
 	// here as they're loaded.
 	states map[string]state.State
@@ -71,6 +78,10 @@ type Local struct {
 	OpInput      bool
 	OpValidation bool
 
+	// CompactWarnings will list each distinct warning from validation just
+	// once, without the explanation that normally comes before them.
+	CompactWarnings bool
+
 	// Backend, if non-nil, will use this backend for non-enhanced behavior.
 	// This allows local behavior with remote state storage. It is a way to
 	// "upgrade" a non-enhanced backend to an enhanced backend with typical
@@ -189,9 +200,19 @@ func (b *Local) State(name string) (state.State, error) {
 			return s, nil
 		}
 
+		enc, err := b.stateEncryption()
+		if err != nil {
+			return nil, err
+		}
+		compress, err := state.CompressBackupsFromEnv()
+		if err != nil {
+			return nil, err
+		}
 		s = &state.BackupState{
-			Real: s,
-			Path: backupPath,
+			Real:       s,
+			Path:       backupPath,
+			Encryption: enc,
+			Compress:   compress,
 		}
 		return s, nil
 	}
@@ -204,17 +225,29 @@ func (b *Local) State(name string) (state.State, error) {
 		return nil, err
 	}
 
+	enc, err := b.stateEncryption()
+	if err != nil {
+		return nil, err
+	}
+
 	// Otherwise, we need to load the state.
 	var s state.State = &state.LocalState{
-		Path:    statePath,
-		PathOut: stateOutPath,
+		Path:       statePath,
+		PathOut:    stateOutPath,
+		Encryption: enc,
 	}
 
 	// If we are backing up the state, wrap it
 	if backupPath != "" {
+		compress, err := state.CompressBackupsFromEnv()
+		if err != nil {
+			return nil, err
+		}
 		s = &state.BackupState{
-			Real: s,
-			Path: backupPath,
+			Real:       s,
+			Path:       backupPath,
+			Encryption: enc,
+			Compress:   compress,
 		}
 	}
 
@@ -345,6 +378,16 @@ func (b *Local) schemaConfigure(ctx context.Context) error {
 	return nil
 }
 
+// stateEncryption returns the StateEncryption to use for local state files,
+// which is StateEncryption if set or else the one configured by the
+// environment.
+func (b *Local) stateEncryption() (state.StateEncryption, error) {
+	if b.StateEncryption != nil {
+		return b.StateEncryption, nil
+	}
+	return state.EncryptionFromEnv()
+}
+
 // StatePaths returns the StatePath, StateOutPath, and StateBackupPath as
 // configured from the CLI.
 func (b *Local) StatePaths(name string) (string, string, string) {
@@ -272,8 +272,21 @@ func (b *Local) opApply(
 func (b *Local) backupStateForError(applyState *terraform.State, err error) error {
 	b.CLI.Error(fmt.Sprintf("Failed to save state: %s\n", err))
 
-	local := &state.LocalState{Path: "errored.tfstate"}
+	// Saving the state somewhere matters more than encrypting it, so if the
+	// encryption can't be configured then the recovery file is written in
+	// plaintext and the user is told so.
+	enc, encErr := b.stateEncryption()
+	if encErr != nil {
+		enc = state.PassthroughEncryption{}
+	}
+	local := &state.LocalState{Path: "errored.tfstate", Encryption: enc}
 	writeErr := local.WriteState(applyState)
+	if writeErr == nil && encErr != nil {
+		b.CLI.Error(fmt.Sprintf(
+			"Failed to configure state encryption: %s\n\n"+
+				"The state in errored.tfstate has been written in plaintext.\n", encErr,
+		))
+	}
 	if writeErr != nil {
 		b.CLI.Error(fmt.Sprintf(
 			"Also failed to create local state file for recovery: %s\n\n", writeErr,
@@ -214,6 +214,45 @@ test_instance.foo:
 	`)
 }
 
+func TestLocal_backupStateForErrorBadEncryption(t *testing.T) {
+	b := TestLocal(t)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current working directory")
+	}
+	err = os.Chdir(filepath.Dir(b.StatePath))
+	if err != nil {
+		t.Fatalf("failed to set temporary working directory")
+	}
+	defer os.Chdir(wd)
+
+	defer os.Setenv(state.StateEncryptionKeyEnvVar, os.Getenv(state.StateEncryptionKeyEnvVar))
+	os.Setenv(state.StateEncryptionKeyEnvVar, "not a key")
+
+	b.CLI = new(cli.MockUi)
+	s := terraform.NewState()
+	s.RootModule().Resources["test_instance.foo"] = &terraform.ResourceState{
+		Type:    "test_instance",
+		Primary: &terraform.InstanceState{ID: "yes"},
+	}
+
+	err = b.backupStateForError(s, errors.New("fake failure"))
+	if err == nil || !strings.Contains(err.Error(), "terraform state push errored.tfstate") {
+		t.Fatalf("wrong error: %v", err)
+	}
+
+	msgStr := b.CLI.(*cli.MockUi).ErrorWriter.String()
+	if !strings.Contains(msgStr, "errored.tfstate has been written in plaintext") {
+		t.Fatalf("missing plaintext warning in output:\n%s", msgStr)
+	}
+
+	// Without encryption, the recovery file must still be readable.
+	checkState(t, "errored.tfstate", `
+test_instance.foo:
+  ID = yes
+	`)
+}
+
 type backendWithFailingState struct {
 	Local
 }
@@ -100,7 +100,12 @@ func (b *Local) context(op *backend.Operation) (*terraform.Context, state.State,
 
 				// If we have a CLI, output the warnings
 				if b.CLI != nil {
-					b.CLI.Warn(strings.TrimSpace(validateWarnHeader) + "\n")
+					if b.CompactWarnings {
+						b.CLI.Warn("Warnings:\n")
+						ws = compactWarnings(ws)
+					} else {
+						b.CLI.Warn(strings.TrimSpace(validateWarnHeader) + "\n")
+					}
 					for _, w := range ws {
 						b.CLI.Warn(fmt.Sprintf("  * %s", w))
 					}
@@ -119,6 +124,29 @@ func (b *Local) context(op *backend.Operation) (*terraform.Context, state.State,
 	return tfCtx, s, nil
 }
 
+// compactWarnings returns each distinct warning from the given list once, in
+// the order they first appear. A warning that appears more than once is
+// annotated with the number of further times it appeared.
+func compactWarnings(ws []string) []string {
+	var distinct []string
+	counts := make(map[string]int, len(ws))
+	for _, w := range ws {
+		if counts[w] == 0 {
+			distinct = append(distinct, w)
+		}
+		counts[w]++
+	}
+
+	ret := make([]string, len(distinct))
+	for i, w := range distinct {
+		ret[i] = w
+		if n := counts[w] - 1; n > 0 {
+			ret[i] = fmt.Sprintf("%s (and %d more like this)", w, n)
+		}
+	}
+	return ret
+}
+
 const validateWarnHeader = `
 There are warnings related to your configuration. If no errors occurred,
 Terraform will continue despite these warnings. It is a good idea to resolve
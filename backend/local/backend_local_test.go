@@ -0,0 +1,28 @@
+package local
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompactWarnings(t *testing.T) {
+	ws := []string{
+		"test_instance.foo: deprecated",
+		"test_instance.bar: deprecated",
+		"test_instance.foo: deprecated",
+		"provider.test: unused",
+		"test_instance.foo: deprecated",
+	}
+	want := []string{
+		"test_instance.foo: deprecated (and 2 more like this)",
+		"test_instance.bar: deprecated",
+		"provider.test: unused",
+	}
+	if got := compactWarnings(ws); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if got := compactWarnings(nil); len(got) != 0 {
+		t.Errorf("wrong result for no warnings: %#v", got)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/helper/experiment"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -90,7 +91,13 @@ func (b *Local) opPlan(
 			b.CLI.Output(b.Colorize().Color(strings.TrimSpace(planRefreshing) + "\n"))
 		}
 
-		_, err := tfCtx.Refresh()
+		var err error
+		if op.PlanRefreshTargeted {
+			err = refreshChanged(tfCtx)
+			countHook.Reset()
+		} else {
+			_, err = tfCtx.Refresh()
+		}
 		if err != nil {
 			runningOp.Err = errwrap.Wrapf("Error refreshing state: {{err}}", err)
 			return
@@ -146,11 +153,21 @@ func (b *Local) opPlan(
 				path))
 		}
 
-		b.CLI.Output(format.Plan(&format.PlanOpts{
+		planOpts := &format.PlanOpts{
 			Plan:        plan,
 			Color:       b.Colorize(),
 			ModuleDepth: -1,
-		}))
+		}
+		if experiment.Enabled(experiment.X_streamPlanRender) {
+			w := &uiOutputWriter{ui: b.CLI}
+			if err := format.PlanStream(w, planOpts); err != nil {
+				runningOp.Err = fmt.Errorf("Error writing plan: %s", err)
+				return
+			}
+			w.Close()
+		} else {
+			b.CLI.Output(format.Plan(planOpts))
+		}
 
 		b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
 			"[reset][bold]Plan:[reset] "+
@@ -161,6 +178,34 @@ func (b *Local) opPlan(
 	}
 }
 
+// refreshChanged refreshes only the resources whose configuration has
+// changed since they were last applied, along with the resources that they
+// depend on.
+//
+// The changed resources are found by planning against the state as it is,
+// without a refresh. That plan is thrown away, and the caller must plan
+// again once the refresh is done.
+func refreshChanged(tfCtx *terraform.Context) error {
+	log.Printf("[INFO] backend/local: plan calling Plan to find changed resources")
+	plan, err := tfCtx.Plan()
+	if err != nil {
+		return err
+	}
+
+	targets, err := plan.Diff.ResourceAddrs()
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		log.Printf("[INFO] backend/local: no changed resources to refresh")
+		return nil
+	}
+
+	log.Printf("[INFO] backend/local: plan calling Refresh for %s", strings.Join(targets, ", "))
+	_, err = tfCtx.RefreshTargets(targets)
+	return err
+}
+
 const planErrNoConfig = `
 No configuration files found!
 
@@ -90,6 +90,62 @@ func TestLocal_planRefreshFalse(t *testing.T) {
 	}
 }
 
+func TestLocal_planRefreshTargeted(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+	terraform.TestStateFile(t, b.StatePath, testPlanState())
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.PlanRefresh = true
+	op.PlanRefreshTargeted = true
+
+	// Nothing has changed, so nothing needs to be refreshed
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if p.RefreshCalled {
+		t.Fatal("refresh should not be called")
+	}
+	if !run.PlanEmpty {
+		t.Fatal("plan should be empty")
+	}
+
+	// Once the resource has changed it must be refreshed
+	p.DiffReturn = &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{
+				Old: "",
+				New: "bar",
+			},
+		},
+	}
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if !p.RefreshCalled {
+		t.Fatal("refresh should be called")
+	}
+	if run.PlanEmpty {
+		t.Fatal("plan should not be empty")
+	}
+}
+
 func TestLocal_planDestroy(t *testing.T) {
 	b := TestLocal(t)
 	p := TestLocalProvider(t, b, "test")
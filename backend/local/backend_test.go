@@ -2,6 +2,7 @@ package local
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -266,6 +267,35 @@ func TestLocal_remoteStateBackup(t *testing.T) {
 	}
 }
 
+// verify that backups are compressed when configured by the environment
+func TestLocal_compressedBackup(t *testing.T) {
+	defer testTmpDir(t)()
+	defer os.Setenv(state.StateBackupCompressEnvVar, os.Getenv(state.StateBackupCompressEnvVar))
+
+	for _, compress := range []bool{false, true} {
+		os.Setenv(state.StateBackupCompressEnvVar, fmt.Sprint(compress))
+
+		b := &Local{}
+		s, err := b.State("default")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bs, ok := s.(*state.BackupState)
+		if !ok {
+			t.Fatal("state is not backed up")
+		}
+		if bs.Compress != compress {
+			t.Fatalf("backup compression is %t; want %t", bs.Compress, compress)
+		}
+	}
+
+	os.Setenv(state.StateBackupCompressEnvVar, "maybe")
+	if _, err := (&Local{}).State("default"); err == nil {
+		t.Fatal("expected error for invalid", state.StateBackupCompressEnvVar)
+	}
+}
+
 // change into a tmp dir and return a deferable func to change back and cleanup
 func testTmpDir(t *testing.T) func() {
 	tmp, err := ioutil.TempDir("", "tf")
@@ -1,7 +1,10 @@
 package local
 
 import (
+	"strings"
+
 	"github.com/hashicorp/terraform/backend"
+	"github.com/mitchellh/cli"
 )
 
 // backend.CLI impl.
@@ -11,6 +14,7 @@ func (b *Local) CLIInit(opts *backend.CLIOpts) error {
 	b.ContextOpts = opts.ContextOpts
 	b.OpInput = opts.Input
 	b.OpValidation = opts.Validation
+	b.CompactWarnings = opts.CompactWarnings
 
 	// Only configure state paths if we didn't do so via the configure func.
 	if b.StatePath == "" {
@@ -21,3 +25,40 @@ func (b *Local) CLIInit(opts *backend.CLIOpts) error {
 
 	return nil
 }
+
+// uiOutputWriter is an io.Writer that passes each line written to it to
+// the Output method of a cli.Ui.
+//
+// Output adds a newline to each message itself, so Close must be called
+// once everything has been written, to pass on the final line whether or
+// not it is empty. The result is then the same as passing everything that
+// was written to Output in a single call.
+type uiOutputWriter struct {
+	ui   cli.Ui
+	line string
+}
+
+func (w *uiOutputWriter) Write(p []byte) (int, error) {
+	return w.WriteString(string(p))
+}
+
+func (w *uiOutputWriter) WriteString(s string) (int, error) {
+	n := len(s)
+	for {
+		idx := strings.IndexByte(s, '\n')
+		if idx < 0 {
+			break
+		}
+		w.ui.Output(w.line + s[:idx])
+		w.line = ""
+		s = s[idx+1:]
+	}
+	w.line += s
+	return n, nil
+}
+
+func (w *uiOutputWriter) Close() error {
+	w.ui.Output(w.line)
+	w.line = ""
+	return nil
+}
@@ -0,0 +1,39 @@
+package local
+
+import (
+	"io"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestUiOutputWriter(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"one line"},
+		{"first", " line\nsecond line\n", "\nfourth line"},
+	}
+
+	for _, writes := range cases {
+		var want string
+		for _, s := range writes {
+			want += s
+		}
+
+		expected := new(cli.MockUi)
+		expected.Output(want)
+
+		ui := new(cli.MockUi)
+		w := &uiOutputWriter{ui: ui}
+		for _, s := range writes {
+			if n, err := io.WriteString(w, s); err != nil || n != len(s) {
+				t.Fatalf("bad write of %q: %d, %s", s, n, err)
+			}
+		}
+		w.Close()
+
+		if got, want := ui.OutputWriter.String(), expected.OutputWriter.String(); got != want {
+			t.Errorf("wrong output for %q\ngot:  %q\nwant: %q", writes, got, want)
+		}
+	}
+}
@@ -33,6 +33,11 @@ func (h *CountHook) Reset() {
 	h.Added = 0
 	h.Changed = 0
 	h.Removed = 0
+
+	h.ToAdd = 0
+	h.ToChange = 0
+	h.ToRemove = 0
+	h.ToRemoveAndAdd = 0
 }
 
 func (h *CountHook) PreApply(
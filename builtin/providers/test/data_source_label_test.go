@@ -3,6 +3,7 @@ package test
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 
@@ -43,3 +44,40 @@ data "test_provider_label" "test" {
 		},
 	})
 }
+
+func TestProviderLabelDataSource_env(t *testing.T) {
+	old, wasSet := os.LookupEnv("TEST_PROVIDER_LABEL")
+	os.Setenv("TEST_PROVIDER_LABEL", "from-env")
+	defer func() {
+		if wasSet {
+			os.Setenv("TEST_PROVIDER_LABEL", old)
+		} else {
+			os.Unsetenv("TEST_PROVIDER_LABEL")
+		}
+	}()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: strings.TrimSpace(`
+data "test_provider_label" "test" {
+}
+				`),
+				Check: func(s *terraform.State) error {
+					res, hasRes := s.RootModule().Resources["data.test_provider_label.test"]
+					if !hasRes {
+						return errors.New("No test_provider_label in state")
+					}
+					if got, want := res.Primary.Attributes["label"], "from-env"; got != want {
+						return fmt.Errorf("wrong label %q; want %q", got, want)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
@@ -11,14 +11,21 @@ func Provider() terraform.ResourceProvider {
 			// Optional attribute to label a particular instance for a test
 			// that has multiple instances of this provider, so that they
 			// can be distinguished using the test_provider_label data source.
+			// If not set in configuration, it is taken from the
+			// TEST_PROVIDER_LABEL environment variable.
 			"label": {
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TEST_PROVIDER_LABEL", nil),
 			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"test_resource":         testResource(),
-			"test_resource_gh12183": testResourceGH12183(),
+			"test_resource":             testResource(),
+			"test_resource_gh12183":     testResourceGH12183(),
+			"test_resource_concurrency": testResourceConcurrency(),
+			"test_resource_fail":        testResourceFail(),
+			"test_resource_reads":       testResourceReads(),
+			"test_resource_timestamp":   testResourceTimestamp(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
 			"test_data_source":    testDataSource(),
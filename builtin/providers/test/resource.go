@@ -26,10 +26,20 @@ func testResource() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			"optional_sensitive": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
 			"optional_bool": {
 				Type:     schema.TypeBool,
 				Optional: true,
 			},
+			"optional_deprecated": {
+				Type:       schema.TypeString,
+				Optional:   true,
+				Deprecated: "optional_deprecated is deprecated; use optional instead",
+			},
 			"optional_force_new": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// This is a test resource to help observe how many operations Terraform
+// runs concurrently, which is used by the end-to-end tests for the
+// -parallelism option. Each create stays active for the given delay, and
+// the highest number of creates seen active at once by this provider
+// process is written to a file called max_concurrency in log_dir.
+func testResourceConcurrency() *schema.Resource {
+	return &schema.Resource{
+		Create: testResourceConcurrencyCreate,
+		Read:   testResourceConcurrencyRead,
+		Delete: testResourceConcurrencyDelete,
+
+		Schema: map[string]*schema.Schema{
+			"log_dir": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"delay": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+				Default:  "100ms",
+			},
+		},
+	}
+}
+
+// concurrency tracks the creates that are currently in progress for all
+// instances of test_resource_concurrency in this process.
+var concurrency struct {
+	sync.Mutex
+	active int
+	max    int
+}
+
+func testResourceConcurrencyCreate(d *schema.ResourceData, meta interface{}) error {
+	delay, err := time.ParseDuration(d.Get("delay").(string))
+	if err != nil {
+		return err
+	}
+
+	concurrency.Lock()
+	concurrency.active++
+	if concurrency.active > concurrency.max {
+		concurrency.max = concurrency.active
+	}
+	path := filepath.Join(d.Get("log_dir").(string), "max_concurrency")
+	err = ioutil.WriteFile(path, []byte(strconv.Itoa(concurrency.max)), 0644)
+	concurrency.Unlock()
+
+	time.Sleep(delay)
+
+	concurrency.Lock()
+	concurrency.active--
+	concurrency.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	d.SetId(resource.UniqueId())
+	return testResourceConcurrencyRead(d, meta)
+}
+
+func testResourceConcurrencyRead(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+func testResourceConcurrencyDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResourceConcurrency_basic(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "tf-test-concurrency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(logDir)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckResourceDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: fmt.Sprintf(`
+resource "test_resource_concurrency" "foo" {
+	count   = 3
+	log_dir = %q
+	delay   = "10ms"
+}
+				`, logDir),
+				Check: func(s *terraform.State) error {
+					src, err := ioutil.ReadFile(filepath.Join(logDir, "max_concurrency"))
+					if err != nil {
+						return err
+					}
+					max, err := strconv.Atoi(string(src))
+					if err != nil {
+						return err
+					}
+					if max < 1 || max > 3 {
+						return fmt.Errorf("impossible max concurrency %d", max)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
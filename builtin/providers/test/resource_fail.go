@@ -0,0 +1,51 @@
+package test
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// This is a test resource whose create fails if asked to, which is used by
+// the end-to-end tests to observe what Terraform persists when an apply
+// fails part way through. A failed create does not set an ID, so the
+// object is not recorded in the state at all.
+func testResourceFail() *schema.Resource {
+	return &schema.Resource{
+		Create: testResourceFailCreate,
+		Read:   testResourceFailRead,
+		Delete: testResourceFailDelete,
+
+		Schema: map[string]*schema.Schema{
+			"fail": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+			},
+			"depends_on_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+func testResourceFailCreate(d *schema.ResourceData, meta interface{}) error {
+	if d.Get("fail").(bool) {
+		return fmt.Errorf("failing as requested")
+	}
+
+	d.SetId(resource.UniqueId())
+	return testResourceFailRead(d, meta)
+}
+
+func testResourceFailRead(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+func testResourceFailDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestResourceFail_basic(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckResourceDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: strings.TrimSpace(`
+resource "test_resource_fail" "foo" {
+}
+				`),
+				Check: resource.TestCheckResourceAttrSet("test_resource_fail.foo", "id"),
+			},
+		},
+	})
+}
+
+func TestResourceFail_fail(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckResourceDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: strings.TrimSpace(`
+resource "test_resource_fail" "foo" {
+	fail = true
+}
+				`),
+				ExpectError: regexp.MustCompile("failing as requested"),
+			},
+		},
+	})
+}
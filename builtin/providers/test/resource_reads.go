@@ -0,0 +1,74 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// This is a test resource to help observe how often Terraform asks the
+// provider to read a resource, which is used by the end-to-end tests for
+// targeted refresh. Each read appends the resource's name, followed by a
+// newline, to a file called reads in log_dir.
+func testResourceReads() *schema.Resource {
+	return &schema.Resource{
+		Create: testResourceReadsCreate,
+		Read:   testResourceReadsRead,
+		Update: testResourceReadsUpdate,
+		Delete: testResourceReadsDelete,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"log_dir": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"value": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+// readsLog serializes the appends to the reads file from all instances of
+// test_resource_reads in this process.
+var readsLog sync.Mutex
+
+func testResourceReadsCreate(d *schema.ResourceData, meta interface{}) error {
+	d.SetId(resource.UniqueId())
+	return testResourceReadsRead(d, meta)
+}
+
+func testResourceReadsRead(d *schema.ResourceData, meta interface{}) error {
+	readsLog.Lock()
+	defer readsLog.Unlock()
+
+	path := filepath.Join(d.Get("log_dir").(string), "reads")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(d.Get("name").(string) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func testResourceReadsUpdate(d *schema.ResourceData, meta interface{}) error {
+	return testResourceReadsRead(d, meta)
+}
+
+func testResourceReadsDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResourceReads_basic(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "tf-test-reads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(logDir)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckResourceDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: fmt.Sprintf(`
+resource "test_resource_reads" "foo" {
+	name    = "foo"
+	log_dir = %q
+}
+				`, logDir),
+				Check: func(s *terraform.State) error {
+					src, err := ioutil.ReadFile(filepath.Join(logDir, "reads"))
+					if err != nil {
+						return err
+					}
+					if len(src) == 0 {
+						return fmt.Errorf("no reads were recorded")
+					}
+					for _, line := range strings.Split(strings.TrimSuffix(string(src), "\n"), "\n") {
+						if line != "foo" {
+							return fmt.Errorf("wrong name recorded for a read: %q", line)
+						}
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// This is a test resource to help observe the order in which Terraform
+// creates resources, which is used by the end-to-end tests for depends_on.
+// Each create writes the times it started and finished, on separate lines
+// in RFC3339 format with nanoseconds, to a file called NAME.timestamps in
+// log_dir. The create stays active for the given delay in between, and
+// fails if that is longer than the create timeout.
+func testResourceTimestamp() *schema.Resource {
+	return &schema.Resource{
+		Create: testResourceTimestampCreate,
+		Read:   testResourceTimestampRead,
+		Delete: testResourceTimestampDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"log_dir": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"delay": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+				Default:  "0s",
+			},
+		},
+	}
+}
+
+func testResourceTimestampCreate(d *schema.ResourceData, meta interface{}) error {
+	delay, err := time.ParseDuration(d.Get("delay").(string))
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	stateConf := &resource.StateChangeConf{
+		Pending: []string{"creating"},
+		Target:  []string{"created"},
+		Refresh: func() (interface{}, string, error) {
+			if time.Since(started) < delay {
+				return struct{}{}, "creating", nil
+			}
+			return struct{}{}, "created", nil
+		},
+		Timeout:      d.Timeout(schema.TimeoutCreate),
+		PollInterval: 10 * time.Millisecond,
+	}
+	if _, err := stateConf.WaitForState(); err != nil {
+		return err
+	}
+	finished := time.Now()
+
+	path := filepath.Join(d.Get("log_dir").(string), d.Get("name").(string)+".timestamps")
+	content := fmt.Sprintf("%s\n%s\n", started.Format(time.RFC3339Nano), finished.Format(time.RFC3339Nano))
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	d.SetId(resource.UniqueId())
+	return testResourceTimestampRead(d, meta)
+}
+
+func testResourceTimestampRead(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+func testResourceTimestampDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResourceTimestamp_basic(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "tf-test-timestamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(logDir)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckResourceDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: fmt.Sprintf(`
+resource "test_resource_timestamp" "foo" {
+	name    = "foo"
+	log_dir = %q
+	delay   = "10ms"
+}
+				`, logDir),
+				Check: func(s *terraform.State) error {
+					src, err := ioutil.ReadFile(filepath.Join(logDir, "foo.timestamps"))
+					if err != nil {
+						return err
+					}
+					lines := strings.Split(strings.TrimSpace(string(src)), "\n")
+					if len(lines) != 2 {
+						return fmt.Errorf("wrong number of timestamps %d; want 2", len(lines))
+					}
+					started, err := time.Parse(time.RFC3339Nano, lines[0])
+					if err != nil {
+						return err
+					}
+					finished, err := time.Parse(time.RFC3339Nano, lines[1])
+					if err != nil {
+						return err
+					}
+					if d := finished.Sub(started); d < 10*time.Millisecond {
+						return fmt.Errorf("create took %s; want at least the 10ms delay", d)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestResourceTimestamp_timeout(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "tf-test-timestamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(logDir)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckResourceDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: fmt.Sprintf(`
+resource "test_resource_timestamp" "foo" {
+	name    = "foo"
+	log_dir = %q
+	delay   = "1s"
+
+	timeouts {
+		create = "50ms"
+	}
+}
+				`, logDir),
+				ExpectError: regexp.MustCompile("timeout while waiting for state to become 'created'"),
+			},
+		},
+	})
+}
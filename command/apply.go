@@ -49,17 +49,23 @@ func (c *ApplyCommand) Run(args []string) int {
 		cmdFlags.BoolVar(&autoApprove, "auto-approve", true, "skip interactive approval of plan before applying")
 	}
 	cmdFlags.IntVar(
-		&c.Meta.parallelism, "parallelism", DefaultParallelism, "parallelism")
+		&c.Meta.parallelism, "parallelism", 0, "parallelism")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
 	cmdFlags.StringVar(&c.Meta.stateOutPath, "state-out", "", "path")
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "", "path")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.BoolVar(&c.Meta.compactWarnings, "compact-warnings", false, "compact warnings")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
 	}
 
+	if c.Meta.parallelism, err = c.Meta.Parallelism(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	// Get the args. The "maybeInit" flag tracks whether we may need to
 	// initialize the configuration from a remote path. This is true as long
 	// as we have an argument.
@@ -216,7 +222,7 @@ func (c *ApplyCommand) Run(args []string) int {
 		}
 
 		if outputs := outputsAsString(op.State, terraform.RootModulePath, mod.Config().Outputs, true); outputs != "" {
-			c.Ui.Output(c.Colorize().Color(outputs))
+			c.redactUi().Output(c.Colorize().Color(outputs))
 		}
 	}
 
@@ -263,6 +269,10 @@ Options:
                          modifying. Defaults to the "-state-out" path with
                          ".backup" extension. Set to "-" to disable backup.
 
+  -compact-warnings      If Terraform produces any warnings that are not
+                         accompanied by errors, list each distinct warning
+                         just once and without the usual explanation.
+
   -lock=true             Lock the state file when locking is supported.
 
   -lock-timeout=0s       Duration to retry a state lock.
@@ -276,7 +286,8 @@ Options:
   -no-color              If specified, output won't contain any color.
 
   -parallelism=n         Limit the number of parallel resource operations.
-                         Defaults to 10.
+                         Defaults to TF_PARALLELISM if set, or otherwise
+                         four per CPU, between 10 and 32.
 
   -refresh=true          Update state prior to checking for differences. This
                          has no effect if a plan file is given to apply.
@@ -316,6 +327,10 @@ Options:
                          modifying. Defaults to the "-state-out" path with
                          ".backup" extension. Set to "-" to disable backup.
 
+  -compact-warnings      If Terraform produces any warnings that are not
+                         accompanied by errors, list each distinct warning
+                         just once and without the usual explanation.
+
   -force                 Don't ask for input for destroy confirmation.
 
   -lock=true             Lock the state file when locking is supported.
@@ -325,7 +340,8 @@ Options:
   -no-color              If specified, output won't contain any color.
 
   -parallelism=n         Limit the number of concurrent operations.
-                         Defaults to 10.
+                         Defaults to TF_PARALLELISM if set, or otherwise
+                         four per CPU, between 10 and 32.
 
   -refresh=true          Update state prior to checking for differences. This
                          has no effect if a plan file is given to apply.
@@ -37,10 +37,36 @@ const DefaultVarsFilename = "terraform.tfvars"
 // DefaultBackupExtension is added to the state file to form the path
 const DefaultBackupExtension = ".backup"
 
-// DefaultParallelism is the limit Terraform places on total parallel
-// operations as it walks the dependency graph.
+// DefaultParallelism is the smallest limit Terraform places on total
+// parallel operations as it walks the dependency graph, if no limit is
+// given with -parallelism or ParallelismEnvVar. On a machine with more
+// than two CPUs the limit is higher; see defaultParallelism.
 const DefaultParallelism = 10
 
+// ParallelismEnvVar is the name of the environment variable that can be
+// used to set the limit on parallel operations for commands that are run
+// without -parallelism.
+const ParallelismEnvVar = "TF_PARALLELISM"
+
+// defaultParallelism returns the limit on parallel operations to use on a
+// machine with the given number of CPUs, if no other limit is given.
+//
+// Most of a walk is spent waiting for providers to respond to API calls
+// rather than using the CPU, so this allows four operations per CPU. It is
+// never less than DefaultParallelism, which was the limit for every
+// machine in earlier versions, and never more than 32, to avoid running
+// into the rate limits of remote APIs on large machines.
+func defaultParallelism(cpus int) int {
+	n := 4 * cpus
+	if n < DefaultParallelism {
+		n = DefaultParallelism
+	}
+	if n > 32 {
+		n = 32
+	}
+	return n
+}
+
 // ErrUnsupportedLocalOp is the common error message shown for operations
 // that require a backend.Local.
 const ErrUnsupportedLocalOp = `The configured backend doesn't support this operation.
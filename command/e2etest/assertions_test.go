@@ -0,0 +1,505 @@
+package e2etest
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+// assertPlanTally fails the given test if the number of resources that the
+// given plan would add, change and destroy doesn't match the given counts.
+//
+// The counts are derived from the plan's diff in the same way as the
+// "Plan: ..." summary line that Terraform prints, so a resource that must be
+// replaced counts as both an add and a destroy, and data sources are not
+// counted at all. This allows tests to verify the plan without depending on
+// the exact wording of the human-oriented output.
+func assertPlanTally(t *testing.T, plan *tfcore.Plan, add, change, destroy int) {
+	gotAdd, gotChange, gotDestroy := planTally(plan)
+	if gotAdd != add || gotChange != change || gotDestroy != destroy {
+		t.Errorf(
+			"wrong plan tally\ngot:  %d to add, %d to change, %d to destroy\nwant: %d to add, %d to change, %d to destroy",
+			gotAdd, gotChange, gotDestroy, add, change, destroy,
+		)
+	}
+}
+
+// planTally is the main implementation of assertPlanTally.
+func planTally(plan *tfcore.Plan) (add, change, destroy int) {
+	if plan.Diff == nil {
+		return 0, 0, 0
+	}
+	for _, mod := range plan.Diff.Modules {
+		for key, diff := range mod.Resources {
+			if strings.HasPrefix(key, "data.") {
+				continue
+			}
+			switch diff.ChangeType() {
+			case tfcore.DiffCreate:
+				add++
+			case tfcore.DiffUpdate:
+				change++
+			case tfcore.DiffDestroy:
+				destroy++
+			case tfcore.DiffDestroyCreate:
+				add++
+				destroy++
+			}
+		}
+	}
+	return add, change, destroy
+}
+
+// planTargets returns the addresses of all of the resource instances that
+// have non-empty diffs in the given plan, in the usual resource address
+// syntax and in the order given by sortedAddresses.
+func planTargets(plan *tfcore.Plan) []string {
+	var addrs []*tfcore.ResourceAddress
+	if plan.Diff == nil {
+		return nil
+	}
+	for _, mod := range plan.Diff.Modules {
+		for key, diff := range mod.Resources {
+			if diff.Empty() {
+				continue
+			}
+			addr, err := tfcore.ParseResourceAddressForInstanceDiff(mod.Path[1:], key)
+			if err != nil {
+				// Should never happen, since Terraform itself wrote this key.
+				panic(err)
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return sortedAddresses(addrs)
+}
+
+// replacementMode returns "DeleteThenCreate" or "CreateThenDelete" to
+// describe how the given plan would replace the resource instance at the
+// given address, or an empty string if the plan would not replace it.
+//
+// The diff itself records only that a replacement is needed, so the order
+// is decided from the create_before_destroy setting of the resource in the
+// configuration that was saved in the plan, as Terraform itself does when
+// the plan is applied.
+func replacementMode(plan *tfcore.Plan, addr string) string {
+	ra, err := tfcore.ParseResourceAddress(addr)
+	if err != nil {
+		panic(err)
+	}
+	if plan.Diff == nil {
+		return ""
+	}
+	path := append([]string{"root"}, ra.Path...)
+	mod := plan.Diff.ModuleByPath(path)
+	if mod == nil {
+		return ""
+	}
+
+	key := ra.Type + "." + ra.Name
+	if ra.Mode == config.DataResourceMode {
+		key = "data." + key
+	}
+	if ra.Index >= 0 {
+		key = fmt.Sprintf("%s.%d", key, ra.Index)
+	}
+	if diff := mod.Resources[key]; diff == nil || diff.ChangeType() != tfcore.DiffDestroyCreate {
+		return ""
+	}
+
+	if tree := plan.Module.Child(ra.Path); tree != nil {
+		for _, rc := range tree.Config().Resources {
+			if rc.Mode == ra.Mode && rc.Type == ra.Type && rc.Name == ra.Name && rc.Lifecycle.CreateBeforeDestroy {
+				return "CreateThenDelete"
+			}
+		}
+	}
+	return "DeleteThenCreate"
+}
+
+// sortedAddresses returns the given resource addresses in the usual
+// resource address syntax, in a deterministic order suitable for comparing
+// with expected results in tests.
+//
+// Addresses are ordered by module path, then by mode with managed resources
+// first, then by resource type, name and instance index. Indexes are
+// compared numerically, so "[2]" sorts before "[10]", and a resource
+// without an index sorts before any of its indexed instances.
+func sortedAddresses(addrs []*tfcore.ResourceAddress) []string {
+	sorted := make([]*tfcore.ResourceAddress, len(addrs))
+	copy(sorted, addrs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		for k := 0; k < len(a.Path) && k < len(b.Path); k++ {
+			if a.Path[k] != b.Path[k] {
+				return a.Path[k] < b.Path[k]
+			}
+		}
+		switch {
+		case len(a.Path) != len(b.Path):
+			return len(a.Path) < len(b.Path)
+		case a.Mode != b.Mode:
+			return a.Mode < b.Mode
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		default:
+			return a.Index < b.Index
+		}
+	})
+
+	ret := make([]string, len(sorted))
+	for i, addr := range sorted {
+		ret[i] = addr.String()
+	}
+	return ret
+}
+
+// assertApplyTally fails the given test if the number of resources that
+// were added, changed and destroyed between the states before and after
+// an operation doesn't match the given counts.
+//
+// A resource whose primary instance ID changed is assumed to have been
+// replaced, and so counts as both an add and a destroy. Data sources are
+// counted in the same way as managed resources, which matches the
+// "Destroy complete!" summary but means that a data source that is re-read
+// with a new ID will count as a replacement.
+func assertApplyTally(t *testing.T, before, after *tfcore.State, add, change, destroy int) {
+	gotAdd, gotChange, gotDestroy := applyTally(before, after)
+	if gotAdd != add || gotChange != change || gotDestroy != destroy {
+		t.Errorf(
+			"wrong apply tally\ngot:  %d added, %d changed, %d destroyed\nwant: %d added, %d changed, %d destroyed",
+			gotAdd, gotChange, gotDestroy, add, change, destroy,
+		)
+	}
+}
+
+// applyTally is the main implementation of assertApplyTally.
+func applyTally(before, after *tfcore.State) (add, change, destroy int) {
+	prev := stateInstances(before)
+	next := stateInstances(after)
+
+	for addr, is := range next {
+		old, exists := prev[addr]
+		switch {
+		case !exists:
+			add++
+		case old.ID != is.ID:
+			add++
+			destroy++
+		case !reflect.DeepEqual(old.Attributes, is.Attributes):
+			change++
+		}
+	}
+	for addr := range prev {
+		if _, exists := next[addr]; !exists {
+			destroy++
+		}
+	}
+	return add, change, destroy
+}
+
+// stateInstances returns the primary instance of each resource in the given
+// state, keyed by a string combining its module path and resource key.
+func stateInstances(state *tfcore.State) map[string]*tfcore.InstanceState {
+	ret := make(map[string]*tfcore.InstanceState)
+	if state == nil {
+		return ret
+	}
+	for _, mod := range state.Modules {
+		prefix := strings.Join(mod.Path, ".")
+		for key, rs := range mod.Resources {
+			if rs.Primary == nil {
+				continue
+			}
+			ret[prefix+"."+key] = rs.Primary
+		}
+	}
+	return ret
+}
+
+// assertInState fails the given test if the latest local state of the given
+// harness has no instances of the resource or module at the given address.
+//
+// The address can be any resource or module address. An address without an
+// index matches every instance of a resource that has count set, and a
+// module address matches every instance in that module and its descendants.
+func assertInState(t *testing.T, tf *terraform, addr string) {
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	instances, err := stateAddrInstances(state, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) == 0 {
+		t.Errorf("%s is not in state", addr)
+	}
+}
+
+// assertNotInState fails the given test if the latest local state of the
+// given harness has any instances of the resource or module at the given
+// address. It is the opposite of assertInState.
+//
+// A resource that is recorded in state without any instances, as can be the
+// case after a failed create, is taken as being absent. So is everything when
+// there is no state file yet.
+func assertNotInState(t *testing.T, tf *terraform, addr string) {
+	state, err := tf.LocalState()
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	instances, err := stateAddrInstances(state, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 0 {
+		t.Errorf("%s is in state: %#v", addr, instances)
+	}
+}
+
+// stateAddrInstances returns the addresses of all of the instances in the
+// given state that match the given address, in the order of sortedAddresses,
+// so that instance indexes are ordered numerically. Resources that are
+// recorded without any instances do not contribute to the result.
+//
+// Unlike "terraform state list", a resource address matches only in the
+// module it names, rather than in every module.
+func stateAddrInstances(state *tfcore.State, addr string) ([]string, error) {
+	if state == nil {
+		return nil, nil
+	}
+	parsed, err := tfcore.ParseResourceAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	filter := &tfcore.StateFilter{State: state}
+	results, err := filter.Filter(addr)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []*tfcore.ResourceAddress
+	for _, r := range results {
+		if _, ok := r.Value.(*tfcore.InstanceState); !ok {
+			continue
+		}
+		if parsed.Type != "" && strings.Join(r.Path, ".") != strings.Join(parsed.Path, ".") {
+			continue
+		}
+		instance, err := tfcore.ParseResourceAddress(r.Address)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, instance)
+	}
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+	return sortedAddresses(addrs), nil
+}
+
+// assertRedacted fails the given test if the given secret appears literally
+// anywhere in the given human-oriented command output, such as the output of
+// "terraform plan" or "terraform apply".
+func assertRedacted(t *testing.T, output, secret string) {
+	if strings.Contains(output, secret) {
+		t.Errorf("sensitive value %q was not redacted from output:\n%s", secret, output)
+	}
+}
+
+func TestPlanTally(t *testing.T) {
+	diff := &tfcore.InstanceDiff{
+		Attributes: map[string]*tfcore.ResourceAttrDiff{
+			"foo": {Old: "a", New: "b"},
+		},
+	}
+	replace := &tfcore.InstanceDiff{
+		Destroy: true,
+		Attributes: map[string]*tfcore.ResourceAttrDiff{
+			"foo": {Old: "a", New: "b", RequiresNew: true},
+		},
+	}
+	create := &tfcore.InstanceDiff{
+		Attributes: map[string]*tfcore.ResourceAttrDiff{
+			"id": {NewComputed: true, RequiresNew: true},
+		},
+	}
+	plan := &tfcore.Plan{
+		Diff: &tfcore.Diff{
+			Modules: []*tfcore.ModuleDiff{
+				{
+					Path: []string{"root"},
+					Resources: map[string]*tfcore.InstanceDiff{
+						"null_resource.create":  create,
+						"null_resource.update":  diff,
+						"null_resource.replace": replace,
+						"null_resource.destroy": {Destroy: true},
+						"data.null_data.read":   create,
+					},
+				},
+				{
+					Path: []string{"root", "child"},
+					Resources: map[string]*tfcore.InstanceDiff{
+						"null_resource.create": create,
+					},
+				},
+			},
+		},
+	}
+
+	add, change, destroy := planTally(plan)
+	if add != 3 || change != 1 || destroy != 2 {
+		t.Errorf("wrong tally %d, %d, %d; want 3, 1, 2", add, change, destroy)
+	}
+}
+
+func TestApplyTally(t *testing.T) {
+	before := tfcore.NewState()
+	before.RootModule().Resources = map[string]*tfcore.ResourceState{
+		"null_resource.unchanged": {Primary: &tfcore.InstanceState{ID: "1"}},
+		"null_resource.updated": {Primary: &tfcore.InstanceState{
+			ID:         "2",
+			Attributes: map[string]string{"foo": "a"},
+		}},
+		"null_resource.replaced":  {Primary: &tfcore.InstanceState{ID: "3"}},
+		"null_resource.destroyed": {Primary: &tfcore.InstanceState{ID: "4"}},
+	}
+	after := tfcore.NewState()
+	after.RootModule().Resources = map[string]*tfcore.ResourceState{
+		"null_resource.unchanged": {Primary: &tfcore.InstanceState{ID: "1"}},
+		"null_resource.updated": {Primary: &tfcore.InstanceState{
+			ID:         "2",
+			Attributes: map[string]string{"foo": "b"},
+		}},
+		"null_resource.replaced": {Primary: &tfcore.InstanceState{ID: "5"}},
+		"null_resource.added":    {Primary: &tfcore.InstanceState{ID: "6"}},
+	}
+	after.AddModule([]string{"root", "child"}).Resources = map[string]*tfcore.ResourceState{
+		"null_resource.added": {Primary: &tfcore.InstanceState{ID: "7"}},
+	}
+
+	add, change, destroy := applyTally(before, after)
+	if add != 3 || change != 1 || destroy != 2 {
+		t.Errorf("wrong tally %d, %d, %d; want 3, 1, 2", add, change, destroy)
+	}
+}
+
+func TestSortedAddresses(t *testing.T) {
+	input := []string{
+		"module.child.test_resource.foo[10]",
+		"test_resource.foo[10]",
+		"data.test_data_source.foo",
+		"test_resource.foo[2]",
+		"module.child.module.grandchild.test_resource.foo",
+		"test_resource.bar",
+		"module.child.test_resource.foo[2]",
+		"test_resource.foo",
+		"module.another.test_resource.foo",
+		"aaa_resource.foo",
+	}
+	want := []string{
+		"aaa_resource.foo",
+		"test_resource.bar",
+		"test_resource.foo",
+		"test_resource.foo[2]",
+		"test_resource.foo[10]",
+		"data.test_data_source.foo",
+		"module.another.test_resource.foo",
+		"module.child.test_resource.foo[2]",
+		"module.child.test_resource.foo[10]",
+		"module.child.module.grandchild.test_resource.foo",
+	}
+
+	var addrs []*tfcore.ResourceAddress
+	for _, s := range input {
+		addr, err := tfcore.ParseResourceAddress(s)
+		if err != nil {
+			t.Fatalf("invalid address %q: %s", s, err)
+		}
+		addrs = append(addrs, addr)
+	}
+
+	got := sortedAddresses(addrs)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestStateAddrInstances(t *testing.T) {
+	state := &tfcore.State{
+		Modules: []*tfcore.ModuleState{
+			{
+				Path: []string{"root"},
+				Resources: map[string]*tfcore.ResourceState{
+					"test_resource.single": {
+						Type:    "test_resource",
+						Primary: &tfcore.InstanceState{ID: "single"},
+					},
+					"test_resource.counted.0": {
+						Type:    "test_resource",
+						Primary: &tfcore.InstanceState{ID: "counted0"},
+					},
+					"test_resource.counted.1": {
+						Type:    "test_resource",
+						Primary: &tfcore.InstanceState{ID: "counted1"},
+					},
+					"test_resource.empty": {
+						Type: "test_resource",
+					},
+					"test_resource.many.2": {
+						Type:    "test_resource",
+						Primary: &tfcore.InstanceState{ID: "many2"},
+					},
+					"test_resource.many.10": {
+						Type:    "test_resource",
+						Primary: &tfcore.InstanceState{ID: "many10"},
+					},
+				},
+			},
+			{
+				Path: []string{"root", "child"},
+				Resources: map[string]*tfcore.ResourceState{
+					"test_resource.single": {
+						Type:    "test_resource",
+						Primary: &tfcore.InstanceState{ID: "child"},
+					},
+				},
+			},
+		},
+	}
+
+	tests := map[string][]string{
+		"test_resource.single":              {"test_resource.single"},
+		"test_resource.counted":             {"test_resource.counted[0]", "test_resource.counted[1]"},
+		"test_resource.counted[1]":          {"test_resource.counted[1]"},
+		"test_resource.counted[2]":          nil,
+		"test_resource.many":                {"test_resource.many[2]", "test_resource.many[10]"},
+		"test_resource.empty":               nil,
+		"test_resource.absent":              nil,
+		"module.child":                      {"module.child.test_resource.single"},
+		"module.child.test_resource.single": {"module.child.test_resource.single"},
+		"module.other":                      nil,
+	}
+	for addr, want := range tests {
+		t.Run(addr, func(t *testing.T) {
+			got, err := stateAddrInstances(state, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package e2etest
+
+import (
+	"testing"
+
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+func TestBackendMigration(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// This version of Terraform has no -migrate-state option. Instead, init
+	// always offers to copy existing state when the backend configuration
+	// changes, and -force-copy accepts that offer without prompting.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	before, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+
+	//// MIGRATE
+	backendConfig := `
+terraform {
+  backend "local" {
+    path = "migrated/terraform.tfstate"
+  }
+}
+`
+	if err := tf.WriteFile("backend.tf", []byte(backendConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = tf.Run("init", "-force-copy", "-input=false")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !tf.FileExists("migrated", "terraform.tfstate") {
+		t.Fatalf("state was not written to the new backend's location")
+	}
+
+	after, err := tf.BackendState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	beforeRS := before.RootModule().Resources["test_resource.foo"]
+	afterRS := after.RootModule().Resources["test_resource.foo"]
+	if afterRS == nil {
+		t.Fatalf("test_resource.foo is missing from the migrated state")
+	}
+	if !afterRS.Primary.Equal(beforeRS.Primary) {
+		t.Errorf("test_resource.foo changed during migration\nbefore: %#v\nafter:  %#v", beforeRS.Primary, afterRS.Primary)
+	}
+
+	//// USE NEW BACKEND
+	// Emptying the file at the old location must make no difference, since
+	// it is the migrated state that drives operations now.
+	if err := tf.SetLocalState(tfcore.NewState()); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, exitCode, err := tf.RunExit("plan", "-detailed-exitcode")
+	if err != nil {
+		t.Fatalf("failed to run plan: %s", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("wrong exit code %d after migration; want 0 for no changes\nstderr:\n%s", exitCode, stderr)
+	}
+}
@@ -0,0 +1,47 @@
+package e2etest
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunContextCancelApply(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("fixture uses the \"sleep\" command, which is not available on Windows")
+	}
+
+	tf := newTerraformWithMirror("slow-apply", testPluginsDir)
+	defer tf.Close()
+
+	stdout, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// The fixture's provisioner sleeps for a couple of minutes, so the
+	// deadline here will pass while apply is still running.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	stdout, stderr, err = tf.RunContext(ctx, "apply")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatalf("apply succeeded; want it to be killed\nstdout:\n%s", stdout)
+	}
+	if cerr, ok := err.(*contextError); !ok || cerr.Ctx != context.DeadlineExceeded {
+		t.Fatalf("wrong error %q; want a *contextError for context.DeadlineExceeded\nstderr:\n%s", err, stderr)
+	}
+
+	// RunContext only returns once the child process has exited, so if we
+	// get here well before the provisioner would've finished then the process
+	// was really killed.
+	if elapsed > time.Minute {
+		t.Errorf("apply took %s to exit after cancellation", elapsed)
+	}
+}
@@ -0,0 +1,81 @@
+package e2etest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCLIConfigDiscovery(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Terraform on Windows doesn't find its home directory from the environment")
+	}
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Rather than being staged in the working
+	// directory, the provider is installed in the global plugin directory
+	// in the fake home, alongside a CLI configuration that sets a redaction
+	// pattern. Both are found only by looking in the home directory.
+
+	tf := newTerraform("redact")
+	defer tf.Close()
+	home := tf.withFakeHome(t)
+
+	pluginDir := filepath.Join(home, ".terraform.d", "plugins", runtime.GOOS+"_"+runtime.GOARCH)
+	if err := os.MkdirAll(pluginDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	infos, err := ioutil.ReadDir(testPluginsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		name := info.Name()
+		if err := copyFile(filepath.Join(pluginDir, name), filepath.Join(testPluginsDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := `redact_patterns = ["tok-[0-9a-f]{8}"]`
+	if err := ioutil.WriteFile(filepath.Join(home, ".terraformrc"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const token = "tok-0123abcd"
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	stdout, stderr, err := tf.RunPlain("plan", "-var", "token="+token)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, token)
+	if !strings.Contains(stdout, `"(redacted)"`) {
+		t.Errorf("CLI configuration from the home directory was not used:\n%s", stdout)
+	}
+
+	//// EXPLICIT CONFIG FILE
+	// A configuration file given in the environment takes the place of the
+	// one in the home directory.
+	empty := filepath.Join(home, "empty.tfrc")
+	if err := ioutil.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err = tf.RunWithEnv([]string{"TERRAFORM_CONFIG=" + empty}, "plan", "-no-color", "-var", "token="+token)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, token) {
+		t.Errorf("CLI configuration from the home directory was used despite TERRAFORM_CONFIG:\n%s", stdout)
+	}
+}
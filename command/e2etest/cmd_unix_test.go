@@ -0,0 +1,22 @@
+// +build !windows
+
+package e2etest
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killOnCancel arranges for the given command, which must have been created
+// with exec.CommandContext, to run in its own process group and for that
+// entire group to be killed if the context is cancelled.
+//
+// Terraform re-executes itself as a child process to catch panics, and also
+// launches plugin processes, so killing only the process we started would
+// leave the real work running and holding our output pipes open.
+func killOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
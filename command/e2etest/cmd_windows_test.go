@@ -0,0 +1,14 @@
+// +build windows
+
+package e2etest
+
+import (
+	"os/exec"
+)
+
+// killOnCancel is a no-op on Windows, where there are no process groups to
+// kill. exec.CommandContext will kill only the process we started, and so we
+// rely on the WaitDelay set by CmdContext to avoid waiting forever for its
+// child processes to close our output pipes.
+func killOnCancel(cmd *exec.Cmd) {
+}
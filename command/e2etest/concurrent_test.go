@@ -0,0 +1,71 @@
+package e2etest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// runConcurrent creates n independent harnesses for the given fixture, each
+// with the "test" provider installed as for newTerraformWithMirror, and then
+// calls fn with each of them concurrently, returning once all of the calls
+// have returned.
+//
+// fn runs on its own goroutine, so it must report problems using t.Errorf
+// rather than t.Fatalf. The harnesses are closed when the test completes.
+func runConcurrent(t *testing.T, n int, fixtureName string, fn func(tf *terraform)) {
+	harnesses := make([]*terraform, n)
+	for i := range harnesses {
+		harnesses[i] = newTerraformWithMirror(fixtureName, testPluginsDir)
+		defer harnesses[i].Close()
+	}
+
+	var wg sync.WaitGroup
+	for _, tf := range harnesses {
+		wg.Add(1)
+		go func(tf *terraform) {
+			defer wg.Done()
+			fn(tf)
+		}(tf)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentApply(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// Each instance has its own working directory, so none of them should
+	// be able to see any of the others. Anything that goes wrong here points
+	// at something shared between Terraform processes, such as a temporary
+	// file with a fixed name. Run it with -race to also check the harness.
+
+	const instances = 8
+	runConcurrent(t, instances, "targeted", func(tf *terraform) {
+		fail := func(format string, args ...interface{}) {
+			t.Errorf("in %s: %s", tf.WorkDir(), fmt.Sprintf(format, args...))
+		}
+
+		_, stderr, err := tf.Run("init")
+		if err != nil {
+			fail("unexpected init error: %s\nstderr:\n%s", err, stderr)
+			return
+		}
+		_, stderr, err = tf.Run("apply")
+		if err != nil {
+			fail("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+			return
+		}
+
+		addrs, err := tf.StateList()
+		if err != nil {
+			fail("failed to list state: %s", err)
+			return
+		}
+		if got, want := len(addrs), 3; got != want {
+			fail("wrong number of resources in state %d; want %d\n%#v", got, want, addrs)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package e2etest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Console evaluates the given expression by passing it to
+// "terraform console" on its standard input, and returns the result with
+// the trailing newline removed.
+//
+// The console evaluates expressions against the configuration and state
+// in the working directory, so it can refer to resources that have already
+// been applied.
+func (t *terraform) Console(expr string) (string, error) {
+	cmd := t.Cmd("console")
+	cmd.Stdin = strings.NewReader(expr + "\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to evaluate %s: %s\n%s", expr, err, stderr.String())
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+func TestConsole(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	wantID, err := tf.StateAttr("test_resource.foo", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"test_resource.foo.id": wantID,
+		`upper("abc")`:         "ABC",
+	}
+	for expr, want := range tests {
+		got, err := tf.Console(expr)
+		if err != nil {
+			t.Errorf("%s", err)
+			continue
+		}
+		if got != want {
+			t.Errorf("wrong result for %s %q; want %q", expr, got, want)
+		}
+	}
+
+	_, err = tf.Console(`nonexistent("abc")`)
+	if err == nil {
+		t.Fatalf("no error for call to unknown function")
+	}
+	if !strings.Contains(err.Error(), "unknown function called: nonexistent") {
+		t.Errorf("wrong error for call to unknown function: %s", err)
+	}
+}
@@ -0,0 +1,76 @@
+package e2etest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCountExpansion(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. This version of Terraform has no for_each,
+	// so count is the only way to expand a resource into several instances.
+
+	tf := newTerraformWithMirror("count", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	steps := []struct {
+		instances string
+		tally     string
+		want      []string
+	}{
+		{
+			"3",
+			"Resources: 3 added, 0 changed, 0 destroyed",
+			[]string{
+				"test_resource.x[0]",
+				"test_resource.x[1]",
+				"test_resource.x[2]",
+			},
+		},
+		{
+			// A resource with a count of one is recorded in state without
+			// an index, so that its address is the same as if it had no
+			// count at all.
+			"1",
+			"Resources: 0 added, 0 changed, 2 destroyed",
+			[]string{
+				"test_resource.x",
+			},
+		},
+		{
+			// Expanding again keeps the existing instance as index zero.
+			"2",
+			"Resources: 1 added, 0 changed, 0 destroyed",
+			[]string{
+				"test_resource.x[0]",
+				"test_resource.x[1]",
+			},
+		},
+	}
+
+	for _, step := range steps {
+		stdout, stderr, err := tf.Run("apply", "-var", "instances="+step.instances)
+		if err != nil {
+			t.Fatalf("unexpected apply error with count %s: %s\nstderr:\n%s", step.instances, err, stderr)
+		}
+		if !strings.Contains(stdout, step.tally) {
+			t.Errorf("incorrect apply tally with count %s; want %q:\n%s", step.instances, step.tally, stdout)
+		}
+
+		got, err := tf.StateList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("wrong resources in state with count %s\ngot:  %#v\nwant: %#v", step.instances, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDataSourceOnly(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Its test_data_source echoes its input as its
+	// output, which stands in for something like template_file here.
+
+	tf := newTerraformWithMirror("data-only", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// APPLY
+	stdout, stderr, err := tf.Run("apply", "-var", "name=e2e")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Resources: 0 added, 0 changed, 0 destroyed") {
+		t.Errorf("incorrect apply tally; want nothing added:\n%s", stdout)
+	}
+
+	got, err := tf.StateAttr("data.test_data_source.greeting", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Hello, e2e!"; got != want {
+		t.Errorf("wrong data source result %q; want %q", got, want)
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	for key := range state.RootModule().Resources {
+		if !strings.HasPrefix(key, "data.") {
+			t.Errorf("unexpected managed resource %s in state", key)
+		}
+	}
+	if output := state.RootModule().Outputs["greeting"]; output == nil || output.Value != "Hello, e2e!" {
+		t.Errorf("wrong value for output \"greeting\": %#v", output)
+	}
+
+	//// APPLY AGAIN
+	// Data sources are read again on every run, but that must not be
+	// reported as a change.
+	stdout, stderr, err = tf.Run("apply", "-var", "name=e2e")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Resources: 0 added, 0 changed, 0 destroyed") {
+		t.Errorf("re-apply was not a no-op:\n%s", stdout)
+	}
+	_, _, exitCode, err := tf.RunExit("plan", "-detailed-exitcode", "-var", "name=e2e")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("plan after re-apply wants changes (exit code %d)", exitCode)
+	}
+}
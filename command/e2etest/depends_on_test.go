@@ -0,0 +1,84 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDependsOnOrdering(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Each test_resource_timestamp records when its
+	// create started and finished, and "a" takes long enough to create that
+	// "b" would certainly start before it finished if they were not ordered.
+
+	tf := newTerraformWithMirror("depends-on", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// WITH DEPENDENCY
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, aFinished := readCreateTimestamps(t, tf, "a")
+	bStarted, _ := readCreateTimestamps(t, tf, "b")
+	if bStarted.Before(aFinished) {
+		t.Errorf("b started at %s, before a finished at %s", bStarted, aFinished)
+	}
+
+	//// WITHOUT DEPENDENCY
+	_, stderr, err = tf.Run("destroy", "-force")
+	if err != nil {
+		t.Fatalf("unexpected destroy error: %s\nstderr:\n%s", err, stderr)
+	}
+	src, err := tf.ReadFile("main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := strings.Replace(string(src), `depends_on = ["test_resource_timestamp.a"]`, "", 1)
+	if err := tf.WriteFile("main.tf", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	aStarted, aFinished := readCreateTimestamps(t, tf, "a")
+	bStarted, _ = readCreateTimestamps(t, tf, "b")
+
+	// Without the dependency the two are created concurrently, so b starts
+	// while a is still being created.
+	if !bStarted.Before(aFinished) {
+		t.Errorf("b started at %s, after a finished at %s (a started at %s); want them to overlap", bStarted, aFinished, aStarted)
+	}
+}
+
+// readCreateTimestamps returns the times that the create of the
+// test_resource_timestamp with the given name started and finished, as
+// recorded in the working directory of the given harness.
+func readCreateTimestamps(t *testing.T, tf *terraform, name string) (started, finished time.Time) {
+	src, err := tf.ReadFile(name + ".timestamps")
+	if err != nil {
+		t.Fatalf("failed to read timestamps for %s: %s", name, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(src)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrong number of timestamps for %s: %q", name, src)
+	}
+	times := make([]time.Time, 2)
+	for i, line := range lines {
+		times[i], err = time.Parse(time.RFC3339Nano, line)
+		if err != nil {
+			t.Fatalf("invalid timestamp for %s: %s", name, err)
+		}
+	}
+	return times[0], times[1]
+}
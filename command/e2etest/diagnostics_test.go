@@ -0,0 +1,209 @@
+package e2etest
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// errorsOccurredRegexp matches the heading that introduces a list of
+// errors, which is also how a nested group of errors is introduced.
+var errorsOccurredRegexp = regexp.MustCompile(`\d+ error\(s\) occurred:$`)
+
+// splitDiagnostics picks out the warnings and errors from the given
+// human-oriented command output, which must have been produced with
+// -no-color.
+//
+// This version of Terraform prints warnings as a bulleted list after a
+// "Warnings:" heading, and errors as a bulleted list after an
+// "N error(s) occurred:" heading. Errors can be nested in groups, such as
+// one group per module, in which case only the innermost messages are
+// returned. Any other line that starts with "Error" is also taken as an
+// error, since many commands report a single error that way.
+func splitDiagnostics(output string) (warnings, errors []string) {
+	var section *[]string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			// Blank lines separate the items in a list without ending it.
+		case line == "Warnings:":
+			section = &warnings
+		case errorsOccurredRegexp.MatchString(line):
+			section = &errors
+		case strings.HasPrefix(line, "* ") && section != nil:
+			if !errorsOccurredRegexp.MatchString(line) {
+				*section = append(*section, strings.TrimPrefix(line, "* "))
+			}
+		case strings.HasPrefix(line, "Error"):
+			section = nil
+			errors = append(errors, line)
+		default:
+			section = nil
+		}
+	}
+	return warnings, errors
+}
+
+func TestSplitDiagnostics(t *testing.T) {
+	tests := map[string]struct {
+		Output   string
+		Warnings []string
+		Errors   []string
+	}{
+		"empty": {
+			"",
+			nil,
+			nil,
+		},
+		"warnings": {
+			`There are warnings related to your configuration.
+
+Warnings:
+
+  * test_resource.foo: "a": [DEPRECATED] a is deprecated
+  * test_resource.foo: "b": [DEPRECATED] b is deprecated
+
+Refreshing Terraform state in-memory prior to plan...
+* not a warning
+`,
+			[]string{
+				`test_resource.foo: "a": [DEPRECATED] a is deprecated`,
+				`test_resource.foo: "b": [DEPRECATED] b is deprecated`,
+			},
+			nil,
+		},
+		"nested errors": {
+			`1 error(s) occurred:
+
+* module root: 2 error(s) occurred:
+
+* output 'bad': unknown variable referenced: 'undeclared'
+* output 'worse': unknown variable referenced: 'missing'
+`,
+			nil,
+			[]string{
+				"output 'bad': unknown variable referenced: 'undeclared'",
+				"output 'worse': unknown variable referenced: 'missing'",
+			},
+		},
+		"single error": {
+			"Error loading state: unexpected EOF\n",
+			nil,
+			[]string{"Error loading state: unexpected EOF"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			warnings, errors := splitDiagnostics(test.Output)
+			if !reflect.DeepEqual(warnings, test.Warnings) {
+				t.Errorf("wrong warnings\ngot:  %#v\nwant: %#v", warnings, test.Warnings)
+			}
+			if !reflect.DeepEqual(errors, test.Errors) {
+				t.Errorf("wrong errors\ngot:  %#v\nwant: %#v", errors, test.Errors)
+			}
+		})
+	}
+}
+
+func TestDiagnosticStreams(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. The fixture sets an attribute that the
+	// provider has deprecated.
+	//
+	// Scripts that capture the result of a command rely on errors being
+	// written to stderr. Warnings don't stop the command, and this version
+	// of Terraform writes them to stdout along with the rest of the output.
+
+	tf := newTerraformWithMirror("deprecated", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// WARNING
+	stdout, stderr, err := tf.RunPlain("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	warnings, errors := splitDiagnostics(stdout)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "[DEPRECATED] optional_deprecated is deprecated") {
+		t.Errorf("wrong warnings on stdout %#v\nstdout:\n%s", warnings, stdout)
+	}
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors on stdout %#v", errors)
+	}
+	if stderr != "" {
+		t.Errorf("unexpected output on stderr:\n%s", stderr)
+	}
+
+	//// ERROR
+	broken := `output "bad" { value = "${var.undeclared}" }`
+	if err := tf.WriteFile("broken.tf", []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err = tf.RunPlain("plan")
+	if err == nil {
+		t.Fatalf("plan succeeded with an invalid configuration")
+	}
+	_, errors = splitDiagnostics(stderr)
+	if len(errors) != 1 || !strings.Contains(errors[0], "unknown variable referenced: 'undeclared'") {
+		t.Errorf("wrong errors on stderr %#v\nstderr:\n%s", errors, stderr)
+	}
+	if _, errors := splitDiagnostics(stdout); len(errors) != 0 {
+		t.Errorf("unexpected errors on stdout %#v", errors)
+	}
+}
+
+func TestCompactWarnings(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Both resources in the fixture set an
+	// attribute that the provider has deprecated.
+
+	tf := newTerraformWithMirror("compact-warnings", testPluginsDir)
+	defer tf.Close()
+	tf.StripColor = false
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	want := []string{
+		`test_resource.bar: "optional_deprecated": [DEPRECATED] optional_deprecated is deprecated; use optional instead`,
+		`test_resource.foo: "optional_deprecated": [DEPRECATED] optional_deprecated is deprecated; use optional instead`,
+	}
+	const explanation = "There are warnings related to your configuration."
+
+	for _, compact := range []bool{false, true} {
+		args := []string{"plan"}
+		if compact {
+			args = append(args, "-compact-warnings")
+		}
+		stdout, stderr, err := tf.RunPlain(args...)
+		if err != nil {
+			t.Fatalf("unexpected %s error: %s\nstderr:\n%s", strings.Join(args, " "), err, stderr)
+		}
+		if strings.Contains(stdout, "\x1b[") {
+			t.Errorf("%s output contains escape sequences despite -no-color:\n%q", strings.Join(args, " "), stdout)
+		}
+
+		warnings, _ := splitDiagnostics(stdout)
+		sort.Strings(warnings)
+		if !reflect.DeepEqual(warnings, want) {
+			t.Errorf("wrong warnings from %s\ngot:  %#v\nwant: %#v", strings.Join(args, " "), warnings, want)
+		}
+		if got := strings.Contains(stdout, explanation); got == compact {
+			t.Errorf("explanation shown is %t from %s; want %t\n%s", got, strings.Join(args, " "), !compact, stdout)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package e2etest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+func TestStateEncryption(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("sensitive", testPluginsDir)
+	defer tf.Close()
+
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x42}, 32))
+	env := []string{state.StateEncryptionKeyEnvVar + "=" + key}
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// APPLY
+	// Applying twice means that there is also a backup file to check.
+	for _, password := range []string{"first-" + secretMarker, "second-" + secretMarker} {
+		_, stderr, err = tf.RunWithEnv(env, "apply", "-var", "password="+password)
+		if err != nil {
+			t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+		}
+	}
+	if !tf.FileExists("terraform.tfstate.backup") {
+		t.Fatalf("no backup file was written")
+	}
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+
+	//// PLAN
+	stdout, stderr, err := tf.RunWithEnv(env, "plan", "-var", "password=second-"+secretMarker)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "No changes") {
+		t.Errorf("plan did not read the encrypted state correctly:\n%s", stdout)
+	}
+
+	//// PLAN WITHOUT KEY
+	_, stderr, err = tf.Run("plan", "-var", "password=second-"+secretMarker)
+	if err == nil {
+		t.Fatalf("plan succeeded without the encryption key")
+	}
+	if !strings.Contains(stderr, "state is encrypted") {
+		t.Errorf("wrong error without the encryption key:\n%s", stderr)
+	}
+
+	//// SHOW
+	// Naming a state file reads it directly rather than through the
+	// backend, so it must be decrypted in the same way.
+	stdout, stderr, err = tf.RunWithEnv(env, "show", "terraform.tfstate")
+	if err != nil {
+		t.Fatalf("unexpected show error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "test_resource.foo") {
+		t.Errorf("show did not read the encrypted state correctly:\n%s", stdout)
+	}
+
+	//// WORKSPACE NEW WITH STATE
+	_, stderr, err = tf.RunWithEnv(env, "workspace", "new", "-state=terraform.tfstate.backup", "copy")
+	if err != nil {
+		t.Fatalf("unexpected workspace new error: %s\nstderr:\n%s", err, stderr)
+	}
+	stdout, stderr, err = tf.RunWithEnv(env, "state", "list")
+	if err != nil {
+		t.Fatalf("unexpected state list error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "test_resource.foo") {
+		t.Errorf("new workspace does not have the state it was created from:\n%s", stdout)
+	}
+
+	//// STATE PUSH
+	_, stderr, err = tf.RunWithEnv(env, "state", "push", "-force", "terraform.tfstate")
+	if err != nil {
+		t.Fatalf("unexpected state push error: %s\nstderr:\n%s", err, stderr)
+	}
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+}
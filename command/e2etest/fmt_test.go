@@ -0,0 +1,61 @@
+package e2etest
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// FmtCheck runs "terraform fmt" in check-only mode over the whole working
+// directory, including subdirectories, and returns the paths of any files
+// that are not in canonical format, relative to the working directory.
+//
+// Files that need formatting do not cause an error. The error return is
+// reserved for real failures, such as files that cannot be parsed.
+func (t *terraform) FmtCheck() (changed []string, err error) {
+	stdout, stderr, err := t.Run("fmt", "-write=false", "-list=true")
+	if err != nil {
+		return nil, fmt.Errorf("%s\nstderr:\n%s", err, stderr)
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			changed = append(changed, line)
+		}
+	}
+	return changed, nil
+}
+
+func TestFmtCheck(t *testing.T) {
+	t.Parallel()
+
+	tf := newTerraform("fmt")
+	defer tf.Close()
+
+	got, err := tf.FmtCheck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join("sub", "bad.tf")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	// Checking must not have rewritten the file.
+	got, err = tf.FmtCheck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result on second check\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if err := tf.WriteFile("broken.tf", []byte("resource {\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tf.FmtCheck(); err == nil {
+		t.Errorf("no error for unparseable file")
+	}
+}
@@ -0,0 +1,120 @@
+package e2etest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// graphEdgeRe matches an edge line in the DOT output of "terraform graph".
+var graphEdgeRe = regexp.MustCompile(`^\s*"([^"]+)" -> "([^"]+)"`)
+
+// GraphEdges runs "terraform graph" and returns its edges as pairs of
+// node names, with each dependent node first and the node it depends on
+// second. The "[root] " prefix that Terraform adds to node names is
+// removed, so the root module's resources appear as just their addresses.
+//
+// Terraform renders graphs with cycles without complaint, so GraphEdges
+// checks for cycles itself and returns an error describing them, rather
+// than returning edges that can't be walked.
+func (t *terraform) GraphEdges(args ...string) ([][2]string, error) {
+	args = append([]string{"graph"}, args...)
+	stdout, stderr, err := t.Run(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s\nstderr:\n%s", err, stderr)
+	}
+
+	var edges [][2]string
+	var g dag.AcyclicGraph
+	for _, line := range strings.Split(stdout, "\n") {
+		m := graphEdgeRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		source := strings.TrimPrefix(m[1], "[root] ")
+		target := strings.TrimPrefix(m[2], "[root] ")
+		edges = append(edges, [2]string{source, target})
+
+		g.Add(source)
+		g.Add(target)
+		g.Connect(dag.BasicEdge(source, target))
+	}
+
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		var msgs []string
+		for _, cycle := range cycles {
+			names := make([]string, len(cycle))
+			for i, v := range cycle {
+				names[i] = dag.VertexName(v)
+			}
+			sort.Strings(names)
+			msgs = append(msgs, strings.Join(names, ", "))
+		}
+		sort.Strings(msgs)
+		return nil, fmt.Errorf("graph has cycles:\n  %s", strings.Join(msgs, "\n  "))
+	}
+
+	return edges, nil
+}
+
+func TestGraphEdges(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("targeted", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	edges, err := tf.GraphEdges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	has := func(source, target string) bool {
+		for _, e := range edges {
+			if e == [2]string{source, target} {
+				return true
+			}
+		}
+		return false
+	}
+	if !has("test_resource.a", "test_resource.dep") {
+		t.Errorf("test_resource.a does not depend on test_resource.dep\n%#v", edges)
+	}
+	if has("test_resource.a", "test_resource.other") || has("test_resource.other", "test_resource.a") {
+		t.Errorf("unexpected edge between test_resource.a and test_resource.other\n%#v", edges)
+	}
+}
+
+func TestGraphEdgesCycle(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("graph-cycle", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	_, err = tf.GraphEdges()
+	if err == nil {
+		t.Fatalf("no error for graph with a cycle")
+	}
+	if got, want := err.Error(), "test_resource.a, test_resource.b"; !strings.Contains(got, want) {
+		t.Errorf("error does not describe the cycle\ngot:  %s\nwant: message containing %q", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package e2etest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHarnessWorkDir(t *testing.T) {
+	t.Parallel()
+
+	tf := newTerraform("empty")
+	dir := tf.WorkDir()
+
+	if !filepath.IsAbs(dir) {
+		t.Errorf("working directory %q is not absolute", dir)
+	}
+	if got := tf.WorkDir(); got != dir {
+		t.Errorf("working directory changed from %q to %q", dir, got)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("working directory %q does not exist: %v", dir, err)
+	}
+
+	tf.Close()
+
+	if keepWorkDirs {
+		return
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("working directory %q still exists after Close", dir)
+	}
+}
+
+func TestHarnessWriteFile(t *testing.T) {
+	t.Parallel()
+
+	// This test generates a configuration that needs no providers, so it
+	// can run without network access.
+
+	tf := newTerraform("empty")
+	defer tf.Close()
+
+	config := []byte(`output "greeting" { value = "hello" }`)
+	if err := tf.WriteFile("main.tf", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nested := []byte("nested content")
+	if err := tf.WriteFile("a/b/c.txt", nested, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !tf.FileExists("a", "b", "c.txt") {
+		t.Fatalf("a/b/c.txt does not exist after WriteFile")
+	}
+	got, err := tf.ReadFile("a", "b", "c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(nested) {
+		t.Errorf("wrong content read back\ngot:  %q\nwant: %q", got, nested)
+	}
+
+	_, stderr, err := tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	output := state.RootModule().Outputs["greeting"]
+	if output == nil || output.Value != "hello" {
+		t.Errorf("wrong greeting output in state: %#v", output)
+	}
+}
+
+func TestHarnessDiffSnapshot(t *testing.T) {
+	t.Parallel()
+
+	tf := newTerraform("empty")
+	defer tf.Close()
+
+	for _, name := range []string{"kept", "changed", "gone"} {
+		if err := tf.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := tf.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tf.WriteFile("changed", []byte("changed content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.WriteFile("new/file", []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(tf.Path("gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	added, modified, removed := tf.DiffSnapshot(before)
+	if want := []string{"new", "new/file"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("wrong added\ngot:  %#v\nwant: %#v", added, want)
+	}
+	if want := []string{"changed"}; !reflect.DeepEqual(modified, want) {
+		t.Errorf("wrong modified\ngot:  %#v\nwant: %#v", modified, want)
+	}
+	if want := []string{"gone"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("wrong removed\ngot:  %#v\nwant: %#v", removed, want)
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "",
+		"plain":                                "plain",
+		"\x1b[1mPlan:\x1b[0m 1 to add":         "Plan: 1 to add",
+		"\x1b[0m\x1b[1m\x1b[32mdone\x1b[0m\n":  "done\n",
+		"\x1b[31mError\x1b[0m\x1b[0m: \x1b[1m": "Error: ",
+	}
+	for input, want := range tests {
+		if got := stripANSI(input); got != want {
+			t.Errorf("wrong result for %q: %q; want %q", input, got, want)
+		}
+	}
+}
+
+func TestHarnessStripColor(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Terraform colors its output by default, so
+	// the escape sequences are there unless the harness removes them.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	stdout, stderr, err := tf.Run("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	if strings.Contains(stdout, "\x1b[") {
+		t.Errorf("escape sequences in output with StripColor set:\n%q", stdout)
+	}
+	if !strings.Contains(stdout, "Plan: 1 to add, 0 to change, 0 to destroy.") {
+		t.Errorf("plan summary is missing from output:\n%s", stdout)
+	}
+
+	tf.StripColor = false
+	stdout, stderr, err = tf.Run("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "\x1b[") {
+		t.Errorf("no escape sequences in output with StripColor unset, so stripping can't be tested:\n%q", stdout)
+	}
+}
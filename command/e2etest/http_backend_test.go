@@ -0,0 +1,136 @@
+package e2etest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+// httpStateServer is a minimal in-memory implementation of the protocol
+// used by the "http" backend, for testing.
+//
+// The backend in this version of Terraform supports only GET to read the
+// state, POST to write it and DELETE to remove it. It does not support
+// locking.
+type httpStateServer struct {
+	mu     sync.Mutex
+	state  []byte
+	posts  int
+	errors []string
+}
+
+func (s *httpStateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case "GET":
+		if s.state == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(s.state)
+	case "POST":
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			s.errors = append(s.errors, err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		hash := md5.Sum(body)
+		if got, want := r.Header.Get("Content-MD5"), base64.StdEncoding.EncodeToString(hash[:]); got != want {
+			s.errors = append(s.errors, "POST with wrong Content-MD5 "+got)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.state = body
+		s.posts++
+	case "DELETE":
+		s.state = nil
+	default:
+		s.errors = append(s.errors, "unsupported method "+r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// State returns the state most recently written to the server, or nil if
+// there is none.
+func (s *httpStateServer) State() (*tfcore.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return nil, nil
+	}
+	return tfcore.ReadState(bytes.NewReader(s.state))
+}
+
+// Posts returns the number of times state has been written to the server.
+func (s *httpStateServer) Posts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.posts
+}
+
+func TestHTTPBackend(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	stateServer := &httpStateServer{}
+	server := httptest.NewServer(stateServer)
+	defer server.Close()
+
+	tf := newTerraformWithMirror("http-backend-apply", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init", "-backend-config=address="+server.URL)
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// APPLY
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if stateServer.Posts() == 0 {
+		t.Fatalf("apply did not POST any state to the server")
+	}
+	state, err := stateServer.State()
+	if err != nil {
+		t.Fatalf("server has invalid state after apply: %s", err)
+	}
+	if state == nil || state.RootModule().Resources["test_resource.foo"] == nil {
+		t.Fatalf("test_resource.foo is missing from the server's state after apply:\n%s", state)
+	}
+	if tf.FileExists("terraform.tfstate") {
+		t.Errorf("state was written to the local filesystem as well as the server")
+	}
+
+	//// DESTROY
+	_, stderr, err = tf.Run("destroy", "-force")
+	if err != nil {
+		t.Fatalf("unexpected destroy error: %s\nstderr:\n%s", err, stderr)
+	}
+	state, err = stateServer.State()
+	if err != nil {
+		t.Fatalf("server has invalid state after destroy: %s", err)
+	}
+	if state != nil && state.HasResources() {
+		t.Errorf("server's state still has resources after destroy:\n%s", state)
+	}
+
+	stateServer.mu.Lock()
+	defer stateServer.mu.Unlock()
+	for _, msg := range stateServer.errors {
+		t.Errorf("bad request to state server: %s", msg)
+	}
+}
@@ -0,0 +1,101 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+// Import runs "terraform import" to import the existing object with the
+// given id into the state at the given resource address.
+func (t *terraform) Import(addr, id string) (stdout, stderr string, err error) {
+	return t.Run("import", addr, id)
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// IMPORT
+	stdout, stderr, err := tf.Import("test_resource.foo", "imported-id")
+	if err != nil {
+		t.Fatalf("unexpected import error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Import successful!") {
+		t.Errorf("success message is missing from output:\n%s", stdout)
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	rs, ok := state.RootModule().Resources["test_resource.foo"]
+	if !ok {
+		t.Fatalf("test_resource.foo is not in the state after import")
+	}
+	if got, want := rs.Primary.ID, "imported-id"; got != want {
+		t.Errorf("wrong ID after import %q; want %q", got, want)
+	}
+
+	//// PLAN
+	// The test provider can't read the configured arguments back from the
+	// imported object, so the first plan fills them in with an in-place
+	// update. It must not try to replace what was just imported, though.
+	_, stderr, err = tf.Run("plan", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	plan, err := tf.Plan("tfplan")
+	if err != nil {
+		t.Fatalf("failed to read plan file: %s", err)
+	}
+	assertPlanTally(t, plan, 0, 1, 0)
+
+	//// APPLY
+	_, stderr, err = tf.Run("apply", "tfplan")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	got, err := tf.StateAttr("test_resource.foo", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "imported-id" {
+		t.Errorf("wrong ID after apply %q; want the imported ID", got)
+	}
+
+	_, _, exitCode, err := tf.RunExit("plan", "-detailed-exitcode")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("plan after applying the import wants changes (exit code %d)", exitCode)
+	}
+
+	//// IMPORT AGAIN
+	stdout, stderr, err = tf.Import("test_resource.foo", "another-id")
+	if err == nil {
+		t.Fatalf("no error when importing to an address already in the state\nstdout:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "would collide with an existing resource") {
+		t.Errorf("error does not explain the collision with the existing resource:\n%s", stderr)
+	}
+
+	got, err = tf.StateAttr("test_resource.foo", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "imported-id" {
+		t.Errorf("failed import changed the ID to %q", got)
+	}
+}
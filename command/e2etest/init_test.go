@@ -1,8 +1,18 @@
 package e2etest
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
 )
 
 func TestInitProviders(t *testing.T) {
@@ -41,3 +51,211 @@ func TestInitProviders(t *testing.T) {
 	}
 
 }
+
+func TestInitProvidersParallel(t *testing.T) {
+	t.Parallel()
+
+	// This test reaches out to releases.hashicorp.com to download three
+	// providers at once, so it can only run if network access is allowed.
+	skipIfCannotAccessNetwork(t)
+
+	tf := newTerraform("multiple-providers")
+	defer tf.Close()
+
+	env := []string{"TF_PLUGIN_INSTALL_PARALLELISM=3"}
+	stdout, stderr, err := tf.RunWithEnv(env, "init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	lockFile := filepath.Join(".terraform", "plugins", runtime.GOOS+"_"+runtime.GOARCH, "lock.json")
+	src, err := tf.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to read plugin lock file: %s", err)
+	}
+	var digests map[string]string
+	if err := json.Unmarshal(src, &digests); err != nil {
+		t.Fatalf("invalid plugin lock file: %s", err)
+	}
+
+	for _, name := range []string{"null", "random", "template"} {
+		if !strings.Contains(stdout, fmt.Sprintf("- Downloading plugin for provider %q", name)) {
+			t.Errorf("provider download message for %s is missing from output:\n%s", name, stdout)
+		}
+		if digests[name] == "" {
+			t.Errorf("no digest recorded for the %s provider in %s", name, src)
+		}
+	}
+	if len(digests) != 3 {
+		t.Errorf("wrong providers in lock file: %s", src)
+	}
+}
+
+func TestInitProviderVersionConstraint(t *testing.T) {
+	t.Parallel()
+
+	// This test reaches out to releases.hashicorp.com to download the
+	// null provider, so it can only run if network access is allowed.
+	skipIfCannotAccessNetwork(t)
+
+	tf := newTerraform("provider-version-constraint")
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	providers, err := tf.Providers()
+	if err != nil {
+		t.Fatalf("failed to read selected providers: %s", err)
+	}
+	got, ok := providers["null"]
+	if !ok {
+		t.Fatalf("no version selected for the null provider\n%#v", providers)
+	}
+
+	v, err := discovery.VersionStr(got).Parse()
+	if err != nil {
+		t.Fatalf("selected version %q is invalid: %s", got, err)
+	}
+	constraint := discovery.ConstraintStr(">= 0.1.0, < 2.0.0").MustParse()
+	if !constraint.Allows(v) {
+		t.Errorf("selected version %s does not satisfy %s", v, constraint)
+	}
+}
+
+// InitUpgrade runs "terraform init -upgrade", which selects the newest
+// available version of each provider that its constraints allow, even if
+// an older version was selected previously.
+func (t *terraform) InitUpgrade() (stdout, stderr string, err error) {
+	return t.Run("init", "-upgrade")
+}
+
+func TestInitUpgrade(t *testing.T) {
+	t.Parallel()
+
+	// This test uses copies of the "test" provider from our own build,
+	// renamed to give them version numbers, so it can run without network
+	// access. The copies must also have different content, because the
+	// version selected is found by matching digests. See Providers.
+	mirrorDir, err := ioutil.TempDir("", "terraform-e2etest-mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mirrorDir)
+	src, err := ioutil.ReadFile(filepath.Join(testPluginsDir, "terraform-provider-test"+exeSuffix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		name := "terraform-provider-test_v" + v + exeSuffix()
+		content := append(src[:len(src):len(src)], v...)
+		if err := ioutil.WriteFile(filepath.Join(mirrorDir, name), content, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tf := newTerraformWithMirror("provider-upgrade", mirrorDir)
+	defer tf.Close()
+
+	assertVersion := func(want string) {
+		providers, err := tf.Providers()
+		if err != nil {
+			t.Fatalf("failed to read selected providers: %s", err)
+		}
+		if got := providers["test"]; got != want {
+			t.Errorf("wrong version selected for the test provider %q; want %q", got, want)
+		}
+	}
+
+	//// INIT
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertVersion("1.1.0")
+
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// UPGRADE WITHIN CONSTRAINT
+	// Nothing newer is allowed by the constraint, so this must keep the
+	// same version. This version of Terraform doesn't report that nothing
+	// changed, so we can only check the result.
+	_, stderr, err = tf.InitUpgrade()
+	if err != nil {
+		t.Fatalf("unexpected init -upgrade error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertVersion("1.1.0")
+
+	//// UPGRADE WITH RELAXED CONSTRAINT
+	config, err := tf.ReadFile("main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = bytes.Replace(config, []byte(`"~> 1.0"`), []byte(`">= 1.0"`), 1)
+	if err := tf.WriteFile("main.tf", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = tf.InitUpgrade()
+	if err != nil {
+		t.Fatalf("unexpected init -upgrade error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertVersion("2.0.0")
+
+	// The newly-selected version must be usable with the existing state.
+	_, stderr, err = tf.Run("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error after upgrade: %s\nstderr:\n%s", err, stderr)
+	}
+}
+
+func TestInitProvidersMirror(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider that is pre-staged from our own
+	// build, so it can run without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	stdout, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	if stderr != "" {
+		t.Errorf("unexpected stderr output:\n%s", stderr)
+	}
+
+	if !strings.Contains(stdout, "Terraform has been successfully initialized!") {
+		t.Errorf("success message is missing from output:\n%s", stdout)
+	}
+
+	if strings.Contains(stdout, "- Downloading plugin for provider") {
+		t.Errorf("init tried to download a plugin that should've been found locally:\n%s", stdout)
+	}
+
+	// Our own build of the test provider has no version in its filename,
+	// so it's treated as version 0.0.0.
+	providers, err := tf.Providers()
+	if err != nil {
+		t.Fatalf("failed to read selected providers: %s", err)
+	}
+	if got, want := providers, map[string]string{"test": "0.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong selected providers\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	// The locally-installed provider should also be usable by other commands.
+	stdout, stderr, err = tf.Run("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	if !strings.Contains(stdout, "1 to add, 0 to change, 0 to destroy") {
+		t.Errorf("incorrect plan tally; want 1 to add:\n%s", stdout)
+	}
+}
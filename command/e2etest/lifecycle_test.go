@@ -0,0 +1,57 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLifecyclePreventDestroy(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("prevent-destroy", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// DESTROY WITH PROTECTION
+	_, stderr, err = tf.Run("destroy", "-force")
+	if err == nil {
+		t.Fatalf("destroy succeeded despite prevent_destroy")
+	}
+	if !strings.Contains(stderr, "test_resource.protected") || !strings.Contains(stderr, "prevent_destroy") {
+		t.Errorf("error does not describe the protected resource:\n%s", stderr)
+	}
+	assertInState(t, tf, "test_resource.protected")
+
+	//// DESTROY WITHOUT PROTECTION
+	unprotected := `
+resource "test_resource" "protected" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+}
+`
+	if err := tf.WriteFile("main.tf", []byte(unprotected), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err := tf.Run("destroy", "-force")
+	if err != nil {
+		t.Fatalf("unexpected destroy error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Resources: 1 destroyed") {
+		t.Errorf("incorrect destroy tally; want 1 destroyed:\n%s", stdout)
+	}
+	assertNotInState(t, tf, "test_resource.protected")
+}
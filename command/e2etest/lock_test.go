@@ -0,0 +1,74 @@
+package e2etest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStateLockConflict(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Its test_resource_concurrency resource takes
+	// a while to create, which keeps the apply below holding the state lock
+	// long enough for us to try to run a second command concurrently.
+
+	tf := newTerraformWithMirror("slow-lock", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	type result struct {
+		stderr string
+		err    error
+	}
+	applyDone := make(chan result, 1)
+	go func() {
+		_, stderr, err := tf.RunContext(ctx, "apply")
+		applyDone <- result{stderr, err}
+	}()
+
+	// The local backend records information about the lock in this file
+	// for as long as it is held.
+	deadline := time.Now().Add(30 * time.Second)
+	for !tf.FileExists(".terraform.tfstate.lock.info") {
+		if time.Now().After(deadline) {
+			t.Fatalf("apply did not acquire the state lock")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	//// CONFLICT
+	_, stderr, err = tf.RunPlain("plan")
+	if err == nil {
+		t.Fatalf("plan succeeded while apply was holding the state lock")
+	}
+	if !strings.Contains(stderr, "Error acquiring the state lock") {
+		t.Errorf("error does not explain the lock conflict:\n%s", stderr)
+	}
+	if !strings.Contains(stderr, "Operation: OperationTypeApply") {
+		t.Errorf("error does not include the lock info:\n%s", stderr)
+	}
+
+	//// RELEASE
+	res := <-applyDone
+	if res.err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", res.err, res.stderr)
+	}
+	if tf.FileExists(".terraform.tfstate.lock.info") {
+		t.Errorf("lock info file still present after apply completed")
+	}
+
+	_, stderr, err = tf.Run("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error after the lock was released: %s\nstderr:\n%s", err, stderr)
+	}
+}
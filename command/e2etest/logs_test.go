@@ -0,0 +1,151 @@
+package e2etest
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// LogLine is a single entry from Terraform's log output.
+type LogLine struct {
+	// Level is the level given in brackets at the start of the message,
+	// such as "INFO" or "TRACE".
+	Level string
+
+	// Message is the remainder of the entry. Some log messages span
+	// multiple lines, in which case the subsequent lines are included
+	// here too.
+	Message string
+
+	// Timestamp is the time the entry was logged, with a resolution of one
+	// second. It is the zero time if the entry had an invalid timestamp.
+	Timestamp time.Time
+}
+
+// logLineRe matches the start of an entry in Terraform's log output, as
+// written by the standard library's log package with a level prefix.
+var logLineRe = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[([A-Z]+)\] (.*)$`)
+
+// RunWithLogs is like Run but also enables Terraform's logging at the TRACE
+// level, sending the logs to a temporary file rather than to stderr, and
+// returns the parsed log entries.
+//
+// This version of Terraform only produces human-oriented log output, so
+// the entries are recovered by parsing the level and timestamp prefix
+// that each one starts with. Lines that don't start a new entry are
+// treated as continuations of the previous entry, so that a malformed line
+// cannot abort the capture of the rest of the log.
+func (t *terraform) RunWithLogs(args ...string) (stdout, stderr string, logs []LogLine, err error) {
+	return t.RunWithEnvAndLogs(nil, args...)
+}
+
+// RunWithEnvAndLogs is like RunWithLogs but also sets the given environment
+// variables, in the same way as RunWithEnv.
+func (t *terraform) RunWithEnvAndLogs(env []string, args ...string) (stdout, stderr string, logs []LogLine, err error) {
+	f, err := ioutil.TempFile("", "terraform-e2etest-log")
+	if err != nil {
+		return "", "", nil, err
+	}
+	logPath := f.Name()
+	f.Close()
+	defer os.Remove(logPath)
+
+	env = append([]string{
+		"TF_LOG=TRACE",
+		"TF_LOG_PATH=" + logPath,
+	}, env...)
+	stdout, stderr, err = t.RunWithEnv(env, args...)
+
+	src, readErr := ioutil.ReadFile(logPath)
+	if readErr != nil && err == nil {
+		err = readErr
+	}
+	return stdout, stderr, parseLogLines(string(src)), err
+}
+
+// parseLogLines is the main implementation of the parsing in RunWithLogs.
+func parseLogLines(src string) []LogLine {
+	var logs []LogLine
+	for _, line := range strings.Split(src, "\n") {
+		m := logLineRe.FindStringSubmatch(line)
+		if m == nil {
+			if len(logs) > 0 && line != "" {
+				prev := &logs[len(logs)-1]
+				prev.Message = prev.Message + "\n" + line
+			}
+			continue
+		}
+
+		ts, _ := time.ParseInLocation("2006/01/02 15:04:05", m[1], time.Local)
+		logs = append(logs, LogLine{
+			Level:     m[2],
+			Message:   m[3],
+			Timestamp: ts,
+		})
+	}
+	return logs
+}
+
+func TestParseLogLines(t *testing.T) {
+	src := "stray line before any entry\n" +
+		"2017/08/01 10:00:00 [INFO] Terraform version: 0.10.1\n" +
+		"2017/08/01 10:00:01 [TRACE] Graph after step:\n" +
+		"\n" +
+		"test_resource.foo - *terraform.NodeAbstractResource\n" +
+		"2017/99/99 10:00:02 [DEBUG] bad timestamp\n" +
+		"2017/08/01 10:00:03 [DEBUG] apply: test_resource.foo: executing Apply\n"
+
+	got := parseLogLines(src)
+	want := []LogLine{
+		{"INFO", "Terraform version: 0.10.1", time.Date(2017, 8, 1, 10, 0, 0, 0, time.Local)},
+		{"TRACE", "Graph after step:\ntest_resource.foo - *terraform.NodeAbstractResource", time.Date(2017, 8, 1, 10, 0, 1, 0, time.Local)},
+		{"DEBUG", "bad timestamp", time.Time{}},
+		{"DEBUG", "apply: test_resource.foo: executing Apply", time.Date(2017, 8, 1, 10, 0, 3, 0, time.Local)},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("wrong number of entries %d; want %d\n%#v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Level != want[i].Level || got[i].Message != want[i].Message || !got[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("wrong entry %d\ngot:  %#v\nwant: %#v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRunWithLogs(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	_, stderr, logs, err := tf.RunWithLogs("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if strings.Contains(stderr, "[TRACE]") {
+		t.Errorf("logs were written to stderr as well as the log file:\n%s", stderr)
+	}
+
+	found := false
+	for _, l := range logs {
+		if l.Level == "DEBUG" && l.Message == "apply: test_resource.foo: executing Apply" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("apply of test_resource.foo was not logged (%d entries captured)", len(logs))
+	}
+}
@@ -2,22 +2,53 @@ package e2etest
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/helper/logging"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/state"
 	tfcore "github.com/hashicorp/terraform/terraform"
 )
 
 var terraformBin string
 
+// testPluginsDir is a directory containing a provider plugin built from
+// the builtin/bins/provider-test package, which allows tests to use the
+// "test" provider without downloading anything. See newTerraformWithMirror.
+var testPluginsDir string
+
 func TestMain(m *testing.M) {
+	// Providers served by serveTestProvider run in this process and log
+	// through the standard logger, as do the parts of Terraform they use.
+	flag.Parse()
+	if testing.Verbose() {
+		// if we're verbose, use the logging requested by TF_LOG
+		logging.SetOutput()
+	} else {
+		// otherwise silence all logs
+		log.SetOutput(ioutil.Discard)
+	}
+
 	teardown := setup()
 	code := m.Run()
+	closeTestProviderServers()
 	teardown()
 	os.Exit(code)
 }
@@ -34,6 +65,12 @@ func setup() func() {
 		if err != nil {
 			panic(fmt.Sprintf("failed to find absolute path of terraform executable: %s", err))
 		}
+		if testPluginsDir != "" {
+			testPluginsDir, err = filepath.Abs(testPluginsDir)
+			if err != nil {
+				panic(fmt.Sprintf("failed to find absolute path of test plugins directory: %s", err))
+			}
+		}
 		return func() {}
 	}
 
@@ -46,27 +83,52 @@ func setup() func() {
 		panic(err)
 	}
 
-	cmd := exec.Command(
-		"go", "build",
-		"-o", tmpFilename,
-		"github.com/hashicorp/terraform",
-	)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-
-	err = cmd.Run()
+	err = goBuild("github.com/hashicorp/terraform", tmpFilename)
 	if err != nil {
 		// The go compiler will have already produced some error messages
 		// on stderr by the time we get here.
 		panic(fmt.Sprintf("failed to build terraform executable: %s", err))
 	}
 
-	// Make the executable available for use in tests
+	pluginsDir, err := ioutil.TempDir("", "terraform-e2etest-plugins")
+	if err != nil {
+		panic(err)
+	}
+
+	err = goBuild(
+		"github.com/hashicorp/terraform/builtin/bins/provider-test",
+		filepath.Join(pluginsDir, "terraform-provider-test"+exeSuffix()),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to build test provider executable: %s", err))
+	}
+
+	// Make the executables available for use in tests
 	terraformBin = tmpFilename
+	testPluginsDir = pluginsDir
 
 	return func() {
 		os.Remove(tmpFilename)
+		os.RemoveAll(pluginsDir)
+	}
+}
+
+// goBuild compiles the main package with the given import path to an
+// executable at the given output path.
+func goBuild(pkg, out string) error {
+	cmd := exec.Command("go", "build", "-o", out, pkg)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+	return cmd.Run()
+}
+
+// exeSuffix returns the suffix that executable files must have on the
+// current platform.
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
 	}
+	return ""
 }
 
 func canAccessNetwork() bool {
@@ -82,13 +144,55 @@ func skipIfCannotAccessNetwork(t *testing.T) {
 	}
 }
 
+// cmdWaitDelay is how long Wait will wait for a Terraform process's output
+// pipes to close after it exits or after its context is cancelled.
+const cmdWaitDelay = 10 * time.Second
+
 // Type terraform represents the combination of a compiled Terraform binary
 // and a temporary working directory to run it in.
 //
-// This is the main harness for tests in this package.
+// This is the main harness for tests in this package. Its methods that run
+// commands may be called concurrently from multiple goroutines, for tests
+// that need to run more than one command in the same working directory at
+// the same time.
 type terraform struct {
 	bin string
 	dir string
+
+	// expectDownloads is true if "terraform init" is expected to download
+	// the providers that the fixture requires, which is the case for a
+	// harness created with newTerraform.
+	//
+	// It is false for a harness created with newTerraformWithMirror,
+	// because the providers are then already present in the working
+	// directory. Tests that check for messages about plugins being
+	// downloaded must consult this rather than assuming, because
+	// newTerraformForProviders may return either kind of harness. This
+	// version of Terraform has no shared plugin cache, so each working
+	// directory is otherwise independent of all others and tests running
+	// in parallel cannot affect one another's downloads.
+	expectDownloads bool
+
+	// home, if set by withFakeHome, is used as the home directory of every
+	// command the harness runs.
+	home string
+
+	// env holds extra environment variables, in the usual "NAME=value"
+	// form, that are set for every command the harness runs. Variables
+	// passed to RunWithEnv override these.
+	env []string
+
+	// reattached are the providers that ReattachProvider has told every
+	// command the harness runs to reattach to, keyed by provider name.
+	reattached map[string]*plugin.ReattachConfig
+
+	// StripColor, which is true by default, causes Run and the other
+	// methods that capture output to remove any terminal escape sequences
+	// from what they return. Terraform colors its output unless -no-color
+	// is given, and tests that match on that output shouldn't need to care.
+	// Tests that check the escape sequences themselves can set this to
+	// false.
+	StripColor bool
 }
 
 // newTerraform prepares a temporary directory containing the files from the
@@ -104,6 +208,10 @@ func newTerraform(fixtureName string) *terraform {
 	if err != nil {
 		panic(err)
 	}
+	tmpDir, err = filepath.Abs(tmpDir)
+	if err != nil {
+		panic(err)
+	}
 
 	// For our purposes here we do a very simplistic file copy that doesn't
 	// attempt to preserve file permissions, attributes, alternate data
@@ -133,37 +241,106 @@ func newTerraform(fixtureName string) *terraform {
 			return os.Mkdir(dstFn, os.ModePerm)
 		}
 
-		src, err := os.Open(srcFn)
-		if err != nil {
-			return err
+		return copyFile(dstFn, srcFn)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &terraform{
+		bin:             terraformBin,
+		dir:             tmpDir,
+		expectDownloads: true,
+		StripColor:      true,
+	}
+}
+
+// newTerraformWithMirror is like newTerraform but also copies all of the
+// plugins in the given directory into the local plugin directory
+// terraform.d/plugins/OS_ARCH within the working directory, where
+// "terraform init" will find them and thus not try to download them.
+//
+// This allows tests to run without network access, either by using the
+// "test" provider from testPluginsDir or by pointing at a directory of
+// pre-staged official provider releases. See newTerraformForProviders for
+// the latter.
+//
+// As with newTerraform, this function will panic if the files cannot be
+// copied.
+func newTerraformWithMirror(fixtureName, mirrorDir string) *terraform {
+	t := newTerraform(fixtureName)
+
+	pluginDir := t.Path("terraform.d", "plugins", runtime.GOOS+"_"+runtime.GOARCH)
+	if err := os.MkdirAll(pluginDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	infos, err := ioutil.ReadDir(mirrorDir)
+	if err != nil {
+		panic(err)
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
 		}
-		dst, err := os.OpenFile(dstFn, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.ModePerm)
+		name := info.Name()
+		err := copyFile(filepath.Join(pluginDir, name), filepath.Join(mirrorDir, name))
 		if err != nil {
-			return err
+			panic(err)
 		}
+	}
 
-		_, err = io.Copy(dst, src)
-		if err != nil {
-			return err
-		}
+	t.expectDownloads = false
+	return t
+}
 
-		if err := src.Close(); err != nil {
-			return err
-		}
-		if err := dst.Close(); err != nil {
-			return err
-		}
+// newTerraformForProviders returns a harness for a fixture that requires
+// official provider releases.
+//
+// If the TF_E2E_PLUGIN_MIRROR environment variable is set, it is taken as
+// the path to a directory containing pre-staged copies of each of the
+// required providers, which are installed using newTerraformWithMirror.
+// Otherwise, the providers will be downloaded from releases.hashicorp.com
+// during "terraform init", and so the given test is skipped unless network
+// access is allowed.
+func newTerraformForProviders(t *testing.T, fixtureName string) *terraform {
+	if mirrorDir := os.Getenv("TF_E2E_PLUGIN_MIRROR"); mirrorDir != "" {
+		return newTerraformWithMirror(fixtureName, mirrorDir)
+	}
+	skipIfCannotAccessNetwork(t)
+	return newTerraform(fixtureName)
+}
 
-		return nil
-	})
+// copyFile is a simplistic file copy helper used to populate working
+// directories. It doesn't attempt to preserve the source file's permissions,
+// instead giving all files full permissions (subject to umask) so that
+// any plugin executables will remain executable.
+func copyFile(dstFn, srcFn string) error {
+	src, err := os.Open(srcFn)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	dst, err := os.OpenFile(dstFn, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		src.Close()
+		return err
 	}
 
-	return &terraform{
-		bin: terraformBin,
-		dir: tmpDir,
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		src.Close()
+		dst.Close()
+		return err
 	}
+
+	if err := src.Close(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // Cmd returns an exec.Cmd pre-configured to run the generated Terraform
@@ -172,7 +349,20 @@ func newTerraform(fixtureName string) *terraform {
 // The returned object can be mutated by the caller to customize how the
 // process will be run, before calling Run.
 func (t *terraform) Cmd(args ...string) *exec.Cmd {
-	cmd := exec.Command(t.bin, args...)
+	return t.CmdContext(context.Background(), args...)
+}
+
+// CmdContext is like Cmd but uses exec.CommandContext, so the child process
+// will be killed if the given context is cancelled or its deadline passes
+// before the process exits on its own.
+//
+// On Unix systems, Terraform is run in its own process group so that any
+// processes it starts are also killed. If any stragglers keep the output
+// pipes open regardless, Wait gives up on them after cmdWaitDelay.
+func (t *terraform) CmdContext(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, t.bin, args...)
+	killOnCancel(cmd)
+	cmd.WaitDelay = cmdWaitDelay
 	cmd.Dir = t.dir
 	cmd.Env = os.Environ()
 
@@ -181,9 +371,50 @@ func (t *terraform) Cmd(args ...string) *exec.Cmd {
 	// end-to-end testing of our Checkpoint interactions.)
 	cmd.Env = append(cmd.Env, "CHECKPOINT_DISABLE=1")
 
+	if t.home != "" {
+		cmd.Env = mergeEnv(cmd.Env, fakeHomeEnv(t.home))
+	}
+	cmd.Env = mergeEnv(cmd.Env, t.env)
+
 	return cmd
 }
 
+// withFakeHome creates an empty temporary directory and arranges for it to
+// be used as the home directory of every command that the harness runs from
+// then on, returning its path. Close removes it along with the working
+// directory.
+//
+// Terraform looks for its CLI configuration file and global plugins in the
+// home directory, so tests that cover that discovery can place files there
+// without reading or changing the real configuration of whoever is running
+// the tests. Any TERRAFORM_CONFIG setting inherited from the test process is
+// cleared so that it doesn't take precedence over the fake home, but
+// variables passed to RunWithEnv still override those set here.
+//
+// Terraform on Windows asks the system for the user's application data
+// directory rather than consulting the environment, so there the fake home
+// has no effect on discovery.
+func (t *terraform) withFakeHome(test *testing.T) string {
+	home, err := ioutil.TempDir("", "terraform-e2etest-home")
+	if err != nil {
+		test.Fatalf("failed to create home directory: %s", err)
+	}
+	t.home = home
+	return home
+}
+
+// fakeHomeEnv returns the environment variables that make the given
+// directory the home directory of a child process, for each of the
+// platforms that Terraform supports.
+func fakeHomeEnv(home string) []string {
+	return []string{
+		"HOME=" + home,
+		"USERPROFILE=" + home,
+		"APPDATA=" + home,
+		"TERRAFORM_CONFIG=",
+	}
+}
+
 // Run executes the generated Terraform binary with the given arguments
 // and returns the bytes that it wrote to both stdout and stderr.
 //
@@ -191,16 +422,310 @@ func (t *terraform) Cmd(args ...string) *exec.Cmd {
 // that don't need any special environment variables. For more complex
 // situations, use Cmd and customize the command before running it.
 func (t *terraform) Run(args ...string) (stdout, stderr string, err error) {
-	cmd := t.Cmd(args...)
+	return t.RunContext(context.Background(), args...)
+}
+
+// RunContext is like Run but will kill the child process if the given
+// context is cancelled or its deadline passes before the process exits.
+//
+// If the process is killed due to the context, the returned error is a
+// *contextError whose Ctx field is ctx.Err(), so that callers can
+// distinguish a timeout or cancellation from Terraform itself exiting with
+// an error. RunContext does not return until the child process has exited.
+func (t *terraform) RunContext(ctx context.Context, args ...string) (stdout, stderr string, err error) {
+	return t.run(ctx, nil, args...)
+}
+
+// RunPlain is like Run but adds the -no-color option after the given
+// arguments, so that Terraform doesn't produce any terminal escape sequences
+// in the first place. This matters for tests that set StripColor to false,
+// and for output that is parsed in ways that are sensitive to the layout of
+// colored text. Terraform removes -no-color wherever it appears, so this
+// works even when the arguments end with a positional argument such as a
+// plan file.
+//
+// Only commands that accept -no-color can be run this way.
+func (t *terraform) RunPlain(args ...string) (stdout, stderr string, err error) {
+	return t.Run(append(args[:len(args):len(args)], "-no-color")...)
+}
+
+// RunWithEnv is like Run but additionally sets the given environment
+// variables, each in the usual "NAME=value" form, for the child process.
+//
+// The given variables are merged over the environment that Cmd would
+// normally use, so that tests can set variables like TF_LOG or TF_VAR_...
+// without modifying the environment of the test process itself. If the
+// same name appears more than once, the last occurrence wins.
+func (t *terraform) RunWithEnv(env []string, args ...string) (stdout, stderr string, err error) {
+	return t.run(context.Background(), env, args...)
+}
+
+// RunWithVars is like Run but additionally sets the given root module
+// variables, by writing them to a temporary variables file in JSON format
+// and adding a -var-file option for it after the given arguments. The
+// file is removed once the command completes.
+//
+// Values are encoded with encoding/json, so lists and maps can be given
+// as []interface{} and map[string]interface{} values without any of the
+// escaping that would be needed to pass them with -var.
+func (t *terraform) RunWithVars(vars map[string]interface{}, args ...string) (stdout, stderr string, err error) {
+	src, err := json.Marshal(vars)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode variables: %s", err)
+	}
+
+	f, err := ioutil.TempFile("", "terraform-e2etest-vars")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(src)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to write variables file: %s", err)
+	}
+
+	args = append(args[:len(args):len(args)], "-var-file="+f.Name())
+	return t.Run(args...)
+}
+
+// RunExit is like Run but also returns the exit code of the child process.
+//
+// Unlike Run, the returned error is nil whenever the child process ran to
+// completion and exited of its own accord, regardless of its exit code. This
+// allows testing commands like "plan -detailed-exitcode" that use specific
+// non-zero exit codes to signal something other than failure.
+//
+// exitCode is -1 if the process could not be started or if it was
+// terminated by a signal rather than exiting normally. In both of those
+// cases err is also non-nil.
+func (t *terraform) RunExit(args ...string) (stdout, stderr string, exitCode int, err error) {
+	stdout, stderr, err = t.Run(args...)
+	if err == nil {
+		return stdout, stderr, 0, nil
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && !status.Signaled() {
+			return stdout, stderr, status.ExitStatus(), nil
+		}
+	}
+
+	return stdout, stderr, -1, err
+}
+
+// RunTimed is like Run but also returns how long the command took, from
+// just before the child process starts until it has exited and all of its
+// output has been read.
+//
+// This is wall-clock time, so it includes any time spent in provider
+// plugins and it varies with the load on the machine. Use assertUnder to
+// compare it against a budget.
+func (t *terraform) RunTimed(args ...string) (stdout, stderr string, d time.Duration, err error) {
+	start := time.Now()
+	stdout, stderr, err = t.Run(args...)
+	return stdout, stderr, time.Since(start), err
+}
+
+// perfStrict is true if the TF_E2E_PERF_STRICT environment variable is set,
+// in which case assertUnder fails the test when a command is too slow. This
+// is intended for a dedicated performance job on a quiet machine, since the
+// timings on a shared or busy machine are too noisy to fail on.
+var perfStrict = os.Getenv("TF_E2E_PERF_STRICT") != ""
+
+// assertUnder reports a command duration d, as returned by RunTimed, that
+// is over the given budget.
+//
+// By default this only logs the overrun, so that a slow machine doesn't
+// fail the whole run. If TF_E2E_PERF_STRICT is set then the test fails
+// instead. Budgets should be generous enough that only a significant
+// regression goes over them.
+func assertUnder(t *testing.T, d, max time.Duration) {
+	if d <= max {
+		return
+	}
+	if perfStrict {
+		t.Errorf("took %s; want under %s", d, max)
+		return
+	}
+	t.Logf("took %s, over the budget of %s; set TF_E2E_PERF_STRICT to fail on this", d, max)
+}
+
+// RunWithRetry is like Run but retries the command up to the given total
+// number of attempts if it fails in a way that looks like a transient
+// network problem, such as a dropped connection or a server error from a
+// remote service. The delay between attempts starts at backoff and doubles
+// after each retry.
+//
+// Failures that are not recognized as transient, such as configuration
+// errors, are returned immediately without retrying. The output returned
+// is always that of the last attempt.
+func (t *terraform) RunWithRetry(attempts int, backoff time.Duration, args ...string) (stdout, stderr string, err error) {
+	for i := 1; ; i++ {
+		stdout, stderr, err = t.Run(args...)
+		if err == nil || i >= attempts || !isTransientFailure(stderr) {
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// transientFailureSignatures are substrings of error messages, in lowercase,
+// that indicate a failure that may succeed if retried.
+var transientFailureSignatures = []string{
+	"connection reset by peer",
+	"connection refused",
+	"i/o timeout",
+	"tls handshake timeout",
+	"unexpected eof",
+	"internal server error",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+	"http error: 50",
+	"response code 50",
+}
+
+// isTransientFailure returns true if the given stderr output from a failed
+// command contains any of transientFailureSignatures.
+func isTransientFailure(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	for _, sig := range transientFailureSignatures {
+		if strings.Contains(stderr, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// run is the shared implementation of the various Run... methods.
+func (t *terraform) run(ctx context.Context, env []string, args ...string) (stdout, stderr string, err error) {
+	cmd := t.CmdContext(ctx, args...)
+	cmd.Env = mergeEnv(cmd.Env, env)
 	cmd.Stdin = nil
 	cmd.Stdout = &bytes.Buffer{}
 	cmd.Stderr = &bytes.Buffer{}
 	err = cmd.Run()
 	stdout = cmd.Stdout.(*bytes.Buffer).String()
 	stderr = cmd.Stderr.(*bytes.Buffer).String()
+	if t.StripColor {
+		stdout = stripANSI(stdout)
+		stderr = stripANSI(stderr)
+	}
+	if err != nil && ctx.Err() != nil {
+		err = &contextError{Ctx: ctx.Err(), Err: err}
+	}
 	return
 }
 
+// contextError is the error returned by the Run... methods when the child
+// process was killed because its context was cancelled or its deadline
+// passed.
+type contextError struct {
+	// Ctx is the error from the context, which is either context.Canceled
+	// or context.DeadlineExceeded.
+	Ctx error
+
+	// Err is the error from waiting for the killed process.
+	Err error
+}
+
+func (e *contextError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Ctx, e.Err)
+}
+
+// WorkspaceNew runs "terraform workspace new" to create a new workspace
+// with the given name, which also selects it.
+func (t *terraform) WorkspaceNew(name string) error {
+	return t.runWorkspace("new", name)
+}
+
+// WorkspaceSelect runs "terraform workspace select" to switch to the
+// existing workspace with the given name.
+func (t *terraform) WorkspaceSelect(name string) error {
+	return t.runWorkspace("select", name)
+}
+
+// WorkspaceDelete runs "terraform workspace delete" to delete the existing
+// workspace with the given name.
+func (t *terraform) WorkspaceDelete(name string) error {
+	return t.runWorkspace("delete", name)
+}
+
+func (t *terraform) runWorkspace(subcmd, name string) error {
+	_, stderr, err := t.Run("workspace", subcmd, name)
+	if err != nil {
+		return fmt.Errorf("workspace %s %s failed: %s\n%s", subcmd, name, err, stderr)
+	}
+	return nil
+}
+
+// WorkspaceList runs "terraform workspace list" and returns the names of
+// the workspaces it reports, in the order they are reported.
+func (t *terraform) WorkspaceList() ([]string, error) {
+	stdout, stderr, err := t.Run("workspace", "list")
+	if err != nil {
+		return nil, fmt.Errorf("workspace list failed: %s\n%s", err, stderr)
+	}
+
+	// Each workspace is listed on its own line, indented by two characters
+	// with the current workspace marked by an asterisk. The list ends at
+	// the first blank line, after which there may be additional notes.
+	var names []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if !strings.HasPrefix(line, "* ") && !strings.HasPrefix(line, "  ") {
+			break
+		}
+		names = append(names, strings.TrimSpace(line[2:]))
+	}
+	return names, nil
+}
+
+// ansiEscapeRegexp matches the ANSI escape sequences that Terraform uses to
+// color its output.
+var ansiEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// stripANSI returns the given string with any ANSI color escape sequences
+// removed.
+func stripANSI(s string) string {
+	return ansiEscapeRegexp.ReplaceAllString(s, "")
+}
+
+// mergeEnv returns a new environment slice with the variables from env
+// applied on top of those in base, with later definitions of a given name
+// replacing earlier ones in-place.
+func mergeEnv(base, env []string) []string {
+	ret := make([]string, 0, len(base)+len(env))
+	idx := make(map[string]int, len(base)+len(env))
+	for _, vars := range [][]string{base, env} {
+		for _, kv := range vars {
+			name := kv
+			if eq := strings.Index(kv, "="); eq >= 0 {
+				name = kv[:eq]
+			}
+			if i, exists := idx[name]; exists {
+				ret[i] = kv
+				continue
+			}
+			idx[name] = len(ret)
+			ret = append(ret, kv)
+		}
+	}
+	return ret
+}
+
+// WorkDir returns the absolute path of the temporary working directory
+// that commands are run in.
+//
+// The result is the same for the lifetime of the object, and the directory
+// and everything in it is deleted by Close.
+func (t *terraform) WorkDir() string {
+	return t.dir
+}
+
 // Path returns a file path within the temporary working directory by
 // appending the given arguments as path segments.
 func (t *terraform) Path(parts ...string) string {
@@ -224,6 +749,24 @@ func (t *terraform) ReadFile(path ...string) ([]byte, error) {
 	return ioutil.ReadFile(flatPath)
 }
 
+// WriteFile is a helper for easily writing a file into the working
+// directory, creating any intermediate directories as needed. The given
+// path is relative to the working directory and uses forward slashes as
+// the separator, regardless of platform.
+//
+// This allows tests to generate configuration and other files at runtime,
+// rather than providing them all in a fixture directory.
+func (t *terraform) WriteFile(relPath string, data []byte, perm os.FileMode) error {
+	flatPath := t.Path(filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(flatPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %s", relPath, err)
+	}
+	if err := ioutil.WriteFile(flatPath, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %s", relPath, err)
+	}
+	return nil
+}
+
 // FileExists is a helper for easily testing whether a particular file
 // exists in the working directory.
 func (t *terraform) FileExists(path ...string) bool {
@@ -232,6 +775,72 @@ func (t *terraform) FileExists(path ...string) bool {
 	return !os.IsNotExist(err)
 }
 
+// Snapshot records the files and directories currently present beneath the
+// working directory, keyed by their paths relative to the working directory
+// using forward slashes as the separator. Pass the result to DiffSnapshot
+// after running a command to find out what that command changed.
+func (t *terraform) Snapshot() (map[string]os.FileInfo, error) {
+	ret := make(map[string]os.FileInfo)
+	err := filepath.Walk(t.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == t.dir {
+			return nil
+		}
+		rel, err := filepath.Rel(t.dir, path)
+		if err != nil {
+			return err
+		}
+		ret[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot working directory: %s", err)
+	}
+	return ret, nil
+}
+
+// DiffSnapshot compares the current contents of the working directory with
+// a snapshot previously returned by Snapshot, returning the sorted paths of
+// the files and directories that have been added, modified or removed since.
+//
+// A file is considered to be modified if its size, mode or modification
+// time has changed. Directories are reported only when they are added or
+// removed, since their modification times change whenever their contents
+// do.
+//
+// DiffSnapshot panics if the working directory cannot be read, in the same
+// way as newTerraform does, since that means the harness itself is broken.
+func (t *terraform) DiffSnapshot(before map[string]os.FileInfo) (added, modified, removed []string) {
+	after, err := t.Snapshot()
+	if err != nil {
+		panic(err)
+	}
+
+	for path, info := range after {
+		prev, exists := before[path]
+		switch {
+		case !exists:
+			added = append(added, path)
+		case info.IsDir() && prev.IsDir():
+			// Nothing to compare; see above.
+		case info.Size() != prev.Size() || info.Mode() != prev.Mode() || !info.ModTime().Equal(prev.ModTime()):
+			modified = append(modified, path)
+		}
+	}
+	for path := range before {
+		if _, exists := after[path]; !exists {
+			removed = append(removed, path)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(modified)
+	sort.Strings(removed)
+	return added, modified, removed
+}
+
 // LocalState is a helper for easily reading the local backend's state file
 // terraform.tfstate from the working directory.
 func (t *terraform) LocalState() (*tfcore.State, error) {
@@ -243,16 +852,285 @@ func (t *terraform) LocalState() (*tfcore.State, error) {
 	return tfcore.ReadState(f)
 }
 
+// LocalBackupState is like LocalState but reads the backup file
+// terraform.tfstate.backup, which holds the state as it was before the most
+// recent command that wrote to terraform.tfstate. If the backup was
+// compressed, terraform.tfstate.backup.gz is read instead.
+//
+// Terraform removes the backup in one format whenever it writes the other,
+// so it is an error for both files to exist: it isn't possible to tell
+// which of them is current.
+func (t *terraform) LocalBackupState() (*tfcore.State, error) {
+	name := "terraform.tfstate.backup"
+	compressed := name + state.CompressedBackupExtension
+	switch plain, gz := t.FileExists(name), t.FileExists(compressed); {
+	case plain && gz:
+		return nil, fmt.Errorf("both %s and %s exist, so the current backup is ambiguous", name, compressed)
+	case gz:
+		name = compressed
+	}
+	if _, err := os.Stat(t.Path(name)); err != nil {
+		return nil, err
+	}
+
+	// The state package's reader is what decompresses the backup.
+	ls := &state.LocalState{Path: t.Path(name)}
+	if err := ls.RefreshState(); err != nil {
+		return nil, err
+	}
+	return ls.State(), nil
+}
+
+// BackendState is a helper for reading the latest state from whatever
+// backend is configured in the working directory, by running
+// "terraform state pull".
+//
+// Unlike LocalState, this works regardless of where the state is stored.
+// If there is no state yet, the result is an empty state.
+func (t *terraform) BackendState() (*tfcore.State, error) {
+	stdout, stderr, err := t.Run("state", "pull")
+	if err != nil {
+		return nil, fmt.Errorf("state pull failed: %s\n%s", err, stderr)
+	}
+	if strings.TrimSpace(stdout) == "" {
+		return tfcore.NewState(), nil
+	}
+	return tfcore.ReadState(strings.NewReader(stdout))
+}
+
+// StatePush replaces the latest state in whatever backend is configured in
+// the working directory with the given state, by passing it to
+// "terraform state push" on its standard input. Any additional arguments,
+// such as -force, are passed to the command before the "-" that tells it to
+// read from standard input.
+//
+// This is the counterpart to BackendState, so a state can be pulled,
+// modified in memory and pushed back. Note that "terraform state push"
+// refuses to replace a state with a different lineage or a higher serial
+// unless it is forced.
+func (t *terraform) StatePush(s *tfcore.State, args ...string) error {
+	var buf bytes.Buffer
+	if err := tfcore.WriteState(s, &buf); err != nil {
+		return fmt.Errorf("failed to encode state: %s", err)
+	}
+
+	cmdArgs := append([]string{"state", "push"}, args...)
+	cmd := t.Cmd(append(cmdArgs, "-")...)
+	cmd.Stdin = &buf
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("state push failed: %s\n%s", err, stripANSI(stderr.String()))
+	}
+	return nil
+}
+
+// StateList runs "terraform state list" and returns the addresses of the
+// resources in the state, in the order given by sortedAddresses.
+func (t *terraform) StateList() ([]string, error) {
+	stdout, stderr, err := t.Run("state", "list")
+	if err != nil {
+		return nil, fmt.Errorf("state list failed: %s\n%s", err, stderr)
+	}
+	var addrs []*tfcore.ResourceAddress
+	for _, line := range strings.Split(stdout, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		addr, err := tfcore.ParseResourceAddress(line)
+		if err != nil {
+			return nil, fmt.Errorf("state list returned invalid address %q: %s", line, err)
+		}
+		addrs = append(addrs, addr)
+	}
+	return sortedAddresses(addrs), nil
+}
+
+// StateMv runs "terraform state mv" to move the resource at one address in
+// the state to another.
+func (t *terraform) StateMv(from, to string) error {
+	_, stderr, err := t.Run("state", "mv", from, to)
+	if err != nil {
+		return fmt.Errorf("state mv failed: %s\n%s", err, stderr)
+	}
+	return nil
+}
+
+// StateRm runs "terraform state rm" to remove the resource at the given
+// address from the state, without destroying it.
+func (t *terraform) StateRm(addr string) error {
+	_, stderr, err := t.Run("state", "rm", addr)
+	if err != nil {
+		return fmt.Errorf("state rm failed: %s\n%s", err, stderr)
+	}
+	return nil
+}
+
+// Replace marks the resource at the given address as tainted, so that the
+// next plan or apply will destroy and recreate it.
+//
+// This version of Terraform has no -replace option for plan and apply, so
+// this runs "terraform taint" instead. That command takes the module path
+// as a separate option rather than as part of the address, so the address
+// is split up here to allow tests to use the usual syntax.
+func (t *terraform) Replace(addr string) error {
+	ra, err := tfcore.ParseResourceAddress(addr)
+	if err != nil {
+		return err
+	}
+	name := ra.Type + "." + ra.Name
+	if ra.Index >= 0 {
+		name = fmt.Sprintf("%s.%d", name, ra.Index)
+	}
+	args := []string{"taint"}
+	if len(ra.Path) > 0 {
+		args = append(args, "-module="+strings.Join(ra.Path, "."))
+	}
+	args = append(args, name)
+
+	_, stderr, err := t.Run(args...)
+	if err != nil {
+		return fmt.Errorf("taint failed: %s\n%s", err, stderr)
+	}
+	return nil
+}
+
+// StateSerial returns the serial number of the local backend's state file,
+// which Terraform increments each time it writes a state that differs from
+// the one it read.
+func (t *terraform) StateSerial() (int64, error) {
+	state, err := t.LocalState()
+	if err != nil {
+		return 0, err
+	}
+	return state.Serial, nil
+}
+
+// StateLineage returns the lineage of the local backend's state file, which
+// Terraform assigns when it first creates a state and then keeps for as long
+// as that state exists.
+func (t *terraform) StateLineage() (string, error) {
+	state, err := t.LocalState()
+	if err != nil {
+		return "", err
+	}
+	return state.Lineage, nil
+}
+
+// StateAttr is a helper for reading a single attribute of the primary
+// instance of a resource in the local backend's state file.
+//
+// The resource is identified by an address in the usual syntax, such as
+// "null_resource.test", "null_resource.test[0]" or
+// "module.child.null_resource.test". If the resource or the attribute is
+// not present in the state, the returned error is a *stateNotFoundError,
+// which allows callers to distinguish that case from an attribute that is
+// present but set to the empty string.
+func (t *terraform) StateAttr(resourceAddr, attrName string) (string, error) {
+	addr, err := tfcore.ParseResourceAddress(resourceAddr)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := t.LocalState()
+	if err != nil {
+		return "", err
+	}
+
+	key := &tfcore.ResourceStateKey{
+		Mode:  addr.Mode,
+		Type:  addr.Type,
+		Name:  addr.Name,
+		Index: addr.Index,
+	}
+
+	var rs *tfcore.ResourceState
+	if mod := state.ModuleByPath(append([]string{"root"}, addr.Path...)); mod != nil {
+		rs = mod.Resources[key.String()]
+	}
+	if rs == nil || rs.Primary == nil {
+		return "", &stateNotFoundError{Addr: resourceAddr}
+	}
+
+	v, ok := rs.Primary.Attributes[attrName]
+	if !ok {
+		return "", &stateNotFoundError{Addr: resourceAddr, Attr: attrName}
+	}
+	return v, nil
+}
+
+// stateNotFoundError is the error type returned by StateAttr when either the
+// requested resource or the requested attribute is not in the state.
+type stateNotFoundError struct {
+	// Addr is the resource address that was requested.
+	Addr string
+
+	// Attr is the attribute that was not found, or the empty string if
+	// it was the resource itself that was not found.
+	Attr string
+}
+
+func (e *stateNotFoundError) Error() string {
+	if e.Attr == "" {
+		return fmt.Sprintf("resource %s is not in the state", e.Addr)
+	}
+	return fmt.Sprintf("resource %s has no attribute %q in the state", e.Addr, e.Attr)
+}
+
+// planFileMagic and planFileVersion are the header that every plan file
+// starts with. These must match the format that terraform.WritePlan
+// produces.
+const (
+	planFileMagic        = "tfplan"
+	planFileVersion byte = 2
+)
+
 // Plan is a helper for easily reading a plan file from the working directory.
+//
+// If the file is a plan file in a format version other than the one this
+// version of Terraform writes, the returned error is a
+// *planVersionMismatchError.
 func (t *terraform) Plan(path ...string) (*tfcore.Plan, error) {
 	f, err := t.OpenFile(path...)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
+
+	header := make([]byte, len(planFileMagic)+1)
+	if _, err := io.ReadFull(f, header); err == nil && string(header[:len(planFileMagic)]) == planFileMagic {
+		if v := header[len(planFileMagic)]; v != planFileVersion {
+			return nil, &planVersionMismatchError{Version: v}
+		}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
 	return tfcore.ReadPlan(f)
 }
 
+// planVersionMismatchError is the error type returned by Plan when a plan
+// file's format version is not the one this version of Terraform supports.
+type planVersionMismatchError struct {
+	// Version is the format version recorded in the plan file.
+	Version byte
+}
+
+func (e *planVersionMismatchError) Error() string {
+	return fmt.Sprintf("plan file has format version %d; want %d", e.Version, planFileVersion)
+}
+
+// SetPlan is a helper for writing the given plan to a file in the working
+// directory, which allows tests to modify plans in ways that Terraform
+// itself never would, such as recording a different Terraform version.
+func (t *terraform) SetPlan(relPath string, plan *tfcore.Plan) error {
+	var buf bytes.Buffer
+	if err := tfcore.WritePlan(plan, &buf); err != nil {
+		return err
+	}
+	return t.WriteFile(relPath, buf.Bytes(), 0644)
+}
+
 // SetLocalState is a helper for easily writing to the file the local backend
 // uses for state in the working directory. This does not go through the
 // actual local backend code, so processing such as management of serials
@@ -273,10 +1151,119 @@ func (t *terraform) SetLocalState(state *tfcore.State) error {
 	return tfcore.WriteState(state, f)
 }
 
+// Providers returns the version of each provider plugin that was selected
+// by "terraform init" in the working directory, keyed by provider name.
+//
+// The plugin lock file records only a digest for each selected plugin, so
+// the version is found by matching that digest against the plugins that
+// Terraform would've searched. If there is no lock file at all then the
+// newest version of each plugin in the auto-install directory is returned
+// instead, as with working directories initialized by older versions.
+func (t *terraform) Providers() (map[string]string, error) {
+	osArch := fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH)
+	autoInstallDir := t.Path(".terraform", "plugins", osArch)
+
+	buf, err := ioutil.ReadFile(filepath.Join(autoInstallDir, "lock.json"))
+	if os.IsNotExist(err) {
+		ret := make(map[string]string)
+		plugins := discovery.FindPlugins("provider", []string{autoInstallDir})
+		for name, metas := range plugins.ByName() {
+			ret[name] = string(metas.Newest().Version)
+		}
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var digests map[string]string
+	if err := json.Unmarshal(buf, &digests); err != nil {
+		return nil, fmt.Errorf("invalid plugin lock file: %s", err)
+	}
+
+	dirs := []string{
+		t.dir,
+		t.Path("terraform.d", "plugins", osArch),
+		autoInstallDir,
+	}
+	plugins := discovery.FindPlugins("provider", dirs).ByName()
+
+	ret := make(map[string]string, len(digests))
+	for name, digest := range digests {
+		for meta := range plugins[name] {
+			got, err := meta.SHA256()
+			if err != nil {
+				return nil, err
+			}
+			if fmt.Sprintf("%x", got) == digest {
+				ret[name] = string(meta.Version)
+				break
+			}
+		}
+		if _, ok := ret[name]; !ok {
+			return nil, fmt.Errorf("no plugin matches the locked digest for provider %q", name)
+		}
+	}
+	return ret, nil
+}
+
+// ProviderHashes returns the digests that "terraform init" recorded for
+// each provider plugin in the working directory, keyed by provider name.
+//
+// This version of Terraform keeps a separate lock file for each platform,
+// in that platform's directory under .terraform/plugins, and records a single
+// SHA256 digest for each provider in each file. The digests from all of the
+// platforms' lock files are returned as sorted hex strings without
+// duplicates. A provider has more than one digest only if the working
+// directory was initialized on more than one platform, as can happen when
+// it is shared. The result is empty if no lock file has been written.
+func (t *terraform) ProviderHashes() (map[string][]string, error) {
+	lockFiles, err := filepath.Glob(t.Path(".terraform", "plugins", "*", "lock.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string][]string)
+	for _, fn := range lockFiles {
+		buf, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		var digests map[string]string
+		if err := json.Unmarshal(buf, &digests); err != nil {
+			return nil, fmt.Errorf("invalid plugin lock file %s: %s", fn, err)
+		}
+		for name, digest := range digests {
+			ret[name] = append(ret[name], digest)
+		}
+	}
+
+	for name, digests := range ret {
+		sort.Strings(digests)
+		unique := digests[:1]
+		for _, digest := range digests[1:] {
+			if digest != unique[len(unique)-1] {
+				unique = append(unique, digest)
+			}
+		}
+		ret[name] = unique
+	}
+	return ret, nil
+}
+
+// keepWorkDirs is true if the TF_E2E_KEEP_DIRS environment variable is set,
+// in which case Close retains each test's working directory rather than
+// deleting it, so that the files that led to a failure can be inspected
+// afterwards.
+var keepWorkDirs = os.Getenv("TF_E2E_KEEP_DIRS") != ""
+
 // Close cleans up the temporary resources associated with the object,
 // including its working directory. It is not valid to call Cmd or Run
 // after Close returns.
 //
+// If TF_E2E_KEEP_DIRS is set then the working directory and any fake home
+// directory are not removed, and their paths are printed to stderr instead.
+//
 // This method does _not_ stop any running child processes. It's the
 // caller's responsibility to also terminate those _before_ closing the
 // underlying terraform object.
@@ -285,5 +1272,15 @@ func (t *terraform) SetLocalState(state *tfcore.State) error {
 // do any error handling and will leave dangling temporary files on disk
 // if any errors occur while cleaning up.
 func (t *terraform) Close() {
+	if keepWorkDirs {
+		fmt.Fprintf(os.Stderr, "retaining working directory %s\n", t.dir)
+		if t.home != "" {
+			fmt.Fprintf(os.Stderr, "retaining home directory %s\n", t.home)
+		}
+		return
+	}
 	os.RemoveAll(t.dir)
+	if t.home != "" {
+		os.RemoveAll(t.home)
+	}
 }
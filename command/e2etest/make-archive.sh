@@ -37,8 +37,13 @@ cp -r test-fixtures "$OUTDIR/test-fixtures"
 # compiler installed.
 go build -o "$OUTDIR/terraform$GOEXE" github.com/hashicorp/terraform
 
+# Likewise for the test provider plugin, which some tests use to avoid
+# downloading providers from releases.hashicorp.com.
+mkdir -p "$OUTDIR/plugins"
+go build -o "$OUTDIR/plugins/terraform-provider-test$GOEXE" github.com/hashicorp/terraform/builtin/bins/provider-test
+
 # Build the test program
-go test -o "$OUTDIR/e2etest$GOEXE" -c -ldflags "-X github.com/hashicorp/terraform/command/e2etest.terraformBin=./terraform$GOEXE" github.com/hashicorp/terraform/command/e2etest
+go test -o "$OUTDIR/e2etest$GOEXE" -c -ldflags "-X github.com/hashicorp/terraform/command/e2etest.terraformBin=./terraform$GOEXE -X github.com/hashicorp/terraform/command/e2etest.testPluginsDir=./plugins" github.com/hashicorp/terraform/command/e2etest
 
 # Now bundle it all together for easy shipping!
 cd "$OUTDIR"
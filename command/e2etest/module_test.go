@@ -0,0 +1,179 @@
+package e2etest
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	getter "github.com/hashicorp/go-getter"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// ModuleRecord describes a module installed in a working directory, as
+// returned by Modules.
+type ModuleRecord struct {
+	// Key is the address of the module, such as "module.a.module.b".
+	Key string
+
+	// Source is the module's source as given in the calling module's
+	// configuration.
+	Source string
+
+	// Dir is the directory the module was loaded from, relative to the
+	// working directory.
+	Dir string
+}
+
+// Modules returns a record for each module called from the configuration
+// in the working directory, including modules called from other modules,
+// in order of their keys. If the configuration calls no modules then the
+// result is empty.
+//
+// This version of Terraform keeps no manifest of installed modules, so the
+// records are found in the same way that Terraform itself finds modules,
+// by loading the configuration from the working directory and locating
+// each module in .terraform/modules. "terraform init" or "terraform get"
+// must therefore have been run first.
+func (t *terraform) Modules() ([]ModuleRecord, error) {
+	tree, err := module.NewTreeModule("", t.dir)
+	if err != nil {
+		return nil, err
+	}
+	storage := &getter.FolderStorage{
+		StorageDir: t.Path(".terraform", "modules"),
+	}
+	if err := tree.Load(storage, module.GetModeNone); err != nil {
+		return nil, err
+	}
+
+	ret := []ModuleRecord{}
+	var walk func(tree *module.Tree, prefix string) error
+	walk = func(tree *module.Tree, prefix string) error {
+		for _, mc := range tree.Config().Modules {
+			child := tree.Children()[mc.Name]
+			dir, err := filepath.Rel(t.dir, child.Config().Dir)
+			if err != nil {
+				return err
+			}
+			key := prefix + "module." + mc.Name
+			ret = append(ret, ModuleRecord{
+				Key:    key,
+				Source: mc.Source,
+				Dir:    filepath.ToSlash(dir),
+			})
+			if err := walk(child, key+"."); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(tree, ""); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Key < ret[j].Key
+	})
+	return ret, nil
+}
+
+func TestModuleLocalSource(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, and its module
+	// is sourced from a local directory, so it can run without network
+	// access.
+
+	tf := newTerraformWithMirror("local-module", testPluginsDir)
+	defer tf.Close()
+
+	//// INIT
+	stdout, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Get: file://") {
+		t.Errorf("module installation message is missing from init output:\n%s", stdout)
+	}
+	if !tf.FileExists(".terraform", "modules") {
+		t.Errorf("init did not install the module into .terraform/modules")
+	}
+
+	//// APPLY
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	got, err := tf.StateList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"module.child.test_resource.test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong resources in state\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	// The variable passed into the module must come back out through the
+	// module's output.
+	outputs, err := tf.Outputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output := outputs["child_greeting"]; output == nil || output.Value != "hello" {
+		t.Errorf("wrong value for output \"child_greeting\": %#v", output)
+	}
+}
+
+func TestModules(t *testing.T) {
+	t.Parallel()
+
+	// This test's modules are sourced from local directories and use no
+	// providers, so it can run without network access.
+
+	t.Run("nested", func(t *testing.T) {
+		tf := newTerraform("nested-modules")
+		defer tf.Close()
+
+		_, stderr, err := tf.Run("init")
+		if err != nil {
+			t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+		}
+
+		got, err := tf.Modules()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotKeys, gotSources []string
+		for _, record := range got {
+			gotKeys = append(gotKeys, record.Key)
+			gotSources = append(gotSources, record.Source)
+			if !strings.HasPrefix(record.Dir, ".terraform/modules/") {
+				t.Errorf("%s was loaded from %q; want a directory in .terraform/modules", record.Key, record.Dir)
+			}
+		}
+		wantKeys := []string{"module.a", "module.a.module.b"}
+		if !reflect.DeepEqual(gotKeys, wantKeys) {
+			t.Errorf("wrong keys\ngot:  %#v\nwant: %#v", gotKeys, wantKeys)
+		}
+		wantSources := []string{"./modules/a", "../b"}
+		if !reflect.DeepEqual(gotSources, wantSources) {
+			t.Errorf("wrong sources\ngot:  %#v\nwant: %#v", gotSources, wantSources)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		tf := newTerraform("var-from-env")
+		defer tf.Close()
+
+		got, err := tf.Modules()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("wrong result %#v; want no modules", got)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package e2etest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+// Outputs runs "terraform output -json" and returns the root module's
+// output values keyed by name.
+//
+// Unlike the human-oriented output, which shows sensitive values as
+// <sensitive>, the JSON output includes the real value of every output
+// along with its Sensitive flag, so tests can make assertions about both.
+//
+// If there is no state yet, or it has no outputs at all, the result is an
+// empty map.
+func (t *terraform) Outputs() (map[string]*tfcore.OutputState, error) {
+	stdout, stderr, err := t.Run("output", "-json")
+	if err != nil {
+		if strings.Contains(stderr, "There is nothing to output") || strings.Contains(stderr, "has no outputs defined") {
+			return map[string]*tfcore.OutputState{}, nil
+		}
+		return nil, fmt.Errorf("%s\nstderr:\n%s", err, stderr)
+	}
+
+	var ret map[string]*tfcore.OutputState
+	if err := json.Unmarshal([]byte(stdout), &ret); err != nil {
+		return nil, fmt.Errorf("invalid output JSON: %s", err)
+	}
+	return ret, nil
+}
+
+func TestOutputs(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("outputs", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	outputs, err := tf.Outputs()
+	if err != nil {
+		t.Fatalf("failed to read outputs before apply: %s", err)
+	}
+	if len(outputs) != 0 {
+		t.Errorf("unexpected outputs before apply\n%#v", outputs)
+	}
+
+	stdout, stderr, err := tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if strings.Contains(stdout, "hunter2") {
+		t.Errorf("sensitive output value was printed by apply:\n%s", stdout)
+	}
+
+	outputs, err = tf.Outputs()
+	if err != nil {
+		t.Fatalf("failed to read outputs after apply: %s", err)
+	}
+
+	tests := map[string]struct {
+		Type      string
+		Value     interface{}
+		Sensitive bool
+	}{
+		"computed": {"string", "value_from_api", false},
+		"secret":   {"string", "hunter2", true},
+		"list":     {"list", []interface{}{"a", "b"}, false},
+		"map":      {"map", map[string]interface{}{"key": "value"}, false},
+	}
+	if len(outputs) != len(tests) {
+		t.Errorf("wrong number of outputs %d; want %d", len(outputs), len(tests))
+	}
+	for name, want := range tests {
+		got, ok := outputs[name]
+		if !ok {
+			t.Errorf("output %q is missing", name)
+			continue
+		}
+		if got.Type != want.Type {
+			t.Errorf("wrong type for %q %q; want %q", name, got.Type, want.Type)
+		}
+		if !reflect.DeepEqual(got.Value, want.Value) {
+			t.Errorf("wrong value for %q\ngot:  %#v\nwant: %#v", name, got.Value, want.Value)
+		}
+		if got.Sensitive != want.Sensitive {
+			t.Errorf("wrong sensitive flag for %q %t; want %t", name, got.Sensitive, want.Sensitive)
+		}
+	}
+}
+
+func TestOutputsWithoutProvider(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("outputs", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// Remove the provider from everywhere init could have put it, and make
+	// sure that it really is gone by checking that plan now fails.
+	for _, dir := range []string{"terraform.d", filepath.Join(".terraform", "plugins")} {
+		if err := os.RemoveAll(tf.Path(dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := tf.Run("plan"); err == nil {
+		t.Fatalf("plan succeeded after the provider was removed")
+	}
+
+	outputs, err := tf.Outputs()
+	if err != nil {
+		t.Fatalf("failed to read outputs without the provider: %s", err)
+	}
+	if got := outputs["computed"]; got == nil || got.Type != "string" || got.Value != "value_from_api" {
+		t.Errorf("wrong output \"computed\": %#v", got)
+	}
+	if got := outputs["list"]; got == nil || got.Type != "list" || !reflect.DeepEqual(got.Value, []interface{}{"a", "b"}) {
+		t.Errorf("wrong output \"list\": %#v", got)
+	}
+}
@@ -0,0 +1,189 @@
+package e2etest
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParallelism(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. The fixture's test_resource_concurrency
+	// instances each take a little while to create, and the provider
+	// records in max_concurrency the largest number that were ever being
+	// created at the same time.
+
+	tf := newTerraformWithMirror("parallelism", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	maxConcurrency := func() int {
+		src, err := tf.ReadFile("max_concurrency")
+		if err != nil {
+			t.Fatalf("failed to read concurrency record: %s", err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(string(src)))
+		if err != nil {
+			t.Fatalf("invalid concurrency record: %s", err)
+		}
+		return n
+	}
+
+	_, stderr, err = tf.Run("apply", "-parallelism=1")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if got := maxConcurrency(); got != 1 {
+		t.Errorf("%d resources were created concurrently with -parallelism=1", got)
+	}
+
+	_, stderr, err = tf.Run("destroy", "-force")
+	if err != nil {
+		t.Fatalf("unexpected destroy error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// Each apply starts a new provider process, so the record starts again
+	// from zero here.
+	_, stderr, err = tf.Run("apply", "-parallelism=10")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if got := maxConcurrency(); got < 2 {
+		t.Errorf("only %d resource was created at a time with -parallelism=10", got)
+	}
+}
+
+// parallelismLogRe matches the log message that records the limit on
+// parallel operations and where it came from.
+var parallelismLogRe = regexp.MustCompile(`^command: parallelism is (\d+) \((.*)\)$`)
+
+func TestParallelismDefault(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("parallelism", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// Without -parallelism or TF_PARALLELISM, the limit depends on the
+	// number of CPUs.
+	want := 4 * runtime.NumCPU()
+	if want < 10 {
+		want = 10
+	}
+	if want > 32 {
+		want = 32
+	}
+
+	tests := []struct {
+		Env  []string
+		Args []string
+		Want string
+	}{
+		{nil, nil, fmt.Sprintf("%d (default for %d CPUs)", want, runtime.NumCPU())},
+		{[]string{"TF_PARALLELISM=3"}, nil, "3 (from TF_PARALLELISM)"},
+		{[]string{"TF_PARALLELISM=3"}, []string{"-parallelism=2"}, "2 (from -parallelism)"},
+	}
+	for _, test := range tests {
+		args := append([]string{"plan"}, test.Args...)
+		_, stderr, logs, err := tf.RunWithEnvAndLogs(test.Env, args...)
+		if err != nil {
+			t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+		}
+
+		var got []string
+		for _, line := range logs {
+			if m := parallelismLogRe.FindStringSubmatch(line.Message); m != nil {
+				got = append(got, m[1]+" ("+m[2]+")")
+			}
+		}
+		if len(got) == 0 {
+			t.Fatalf("parallelism not logged with %v %s", test.Env, strings.Join(args, " "))
+		}
+		for _, g := range got {
+			if g != test.Want {
+				t.Errorf("wrong parallelism logged with %v %s: %s; want %s", test.Env, strings.Join(args, " "), g, test.Want)
+			}
+		}
+	}
+
+	// The environment variable must also actually limit the walk.
+	_, stderr, err = tf.RunWithEnv([]string{"TF_PARALLELISM=1"}, "apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	src, err := tf.ReadFile("max_concurrency")
+	if err != nil {
+		t.Fatalf("failed to read concurrency record: %s", err)
+	}
+	if got := strings.TrimSpace(string(src)); got != "1" {
+		t.Errorf("%s resources were created concurrently with TF_PARALLELISM=1", got)
+	}
+
+	// Commands that don't walk a graph have no use for the limit, so they
+	// neither resolve nor log it, and an invalid value doesn't stop them.
+	for _, args := range [][]string{{"state", "list"}, {"show"}, {"workspace", "list"}, {"state", "pull"}} {
+		_, stderr, logs, err := tf.RunWithEnvAndLogs([]string{"TF_PARALLELISM=lots"}, args...)
+		if err != nil {
+			t.Fatalf("unexpected %s error with an invalid TF_PARALLELISM: %s\nstderr:\n%s", strings.Join(args, " "), err, stderr)
+		}
+		for _, line := range logs {
+			if parallelismLogRe.MatchString(line.Message) {
+				t.Errorf("%s logged a parallelism limit: %s", strings.Join(args, " "), line.Message)
+			}
+		}
+	}
+}
+
+// BenchmarkApplyParallelism compares the time taken to create resources
+// that each spend a while waiting, as they would for a remote API, with
+// the old fixed limit of 10, the largest default of 32 and the default for
+// this machine.
+func BenchmarkApplyParallelism(b *testing.B) {
+	tf := newTerraformWithMirror("parallelism", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		b.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	vars := []string{"-var", "instances=64", "-var", "delay=100ms"}
+	for _, par := range []string{"10", "32", "default"} {
+		b.Run(par, func(b *testing.B) {
+			var flags []string
+			if par != "default" {
+				flags = []string{"-parallelism=" + par}
+			}
+			apply := append(append([]string{"apply"}, vars...), flags...)
+			destroy := append(append([]string{"destroy", "-force"}, vars...), flags...)
+
+			for i := 0; i < b.N; i++ {
+				if _, stderr, err := tf.Run(apply...); err != nil {
+					b.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+				}
+
+				b.StopTimer()
+				if _, stderr, err := tf.Run(destroy...); err != nil {
+					b.Fatalf("unexpected destroy error: %s\nstderr:\n%s", err, stderr)
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package e2etest
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestApplyPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("partial-apply", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	_, stderr, exitCode, err := tf.RunExit("apply")
+	if err != nil {
+		t.Fatalf("failed to run apply: %s", err)
+	}
+	if exitCode != 1 {
+		t.Errorf("wrong exit code %d; want 1\nstderr:\n%s", exitCode, stderr)
+	}
+	if !strings.Contains(stderr, "failing as requested") {
+		t.Errorf("provider's error message is missing from output:\n%s", stderr)
+	}
+
+	// Everything that was created before the failure, or that doesn't
+	// depend on the failing resource at all, must be persisted. The failing
+	// resource and its dependents must not be.
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file after failed apply: %s", err)
+	}
+	var got []string
+	for key := range state.RootModule().Resources {
+		got = append(got, key)
+	}
+	sort.Strings(got)
+	want := []string{
+		"test_resource_fail.before",
+		"test_resource_fail.unrelated",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong resources in state after failed apply\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	// A subsequent plan must only propose what is still missing.
+	_, stderr, err = tf.Run("plan", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	plan, err := tf.Plan("tfplan")
+	if err != nil {
+		t.Fatalf("failed to read plan file: %s", err)
+	}
+	wantTargets := []string{
+		"test_resource_fail.dependent",
+		"test_resource_fail.failing",
+	}
+	if got := planTargets(plan); !reflect.DeepEqual(got, wantTargets) {
+		t.Errorf("wrong resources in plan after failed apply\ngot:  %#v\nwant: %#v", got, wantTargets)
+	}
+}
@@ -0,0 +1,371 @@
+package e2etest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+func TestPlanDetailedExitCode(t *testing.T) {
+	t.Parallel()
+
+	// This test uses only variables and outputs, so it does not need any
+	// providers and can run without network access.
+
+	t.Run("no changes", func(t *testing.T) {
+		tf := newTerraform("var-from-env")
+		defer tf.Close()
+
+		stdout, stderr, code, err := tf.RunExit("plan", "-detailed-exitcode", "-input=false", "-var", "foo=bar")
+		if err != nil {
+			t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+		}
+		if code != 0 {
+			t.Errorf("wrong exit code %d; want 0\nstdout:\n%s\nstderr:\n%s", code, stdout, stderr)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		// With no configuration files at all, plan fails.
+		tf := newTerraform("empty")
+		defer tf.Close()
+
+		stdout, stderr, code, err := tf.RunExit("plan", "-detailed-exitcode", "-input=false")
+		if err != nil {
+			t.Fatalf("unexpected error running plan: %s\nstderr:\n%s", err, stderr)
+		}
+		if code != 1 {
+			t.Errorf("wrong exit code %d; want 1\nstdout:\n%s\nstderr:\n%s", code, stdout, stderr)
+		}
+	})
+
+	t.Run("after apply", func(t *testing.T) {
+		// This test uses the "test" provider from our own build, so it can
+		// run without network access.
+		tf := newTerraformWithMirror("test-provider", testPluginsDir)
+		defer tf.Close()
+
+		_, stderr, err := tf.Run("init")
+		if err != nil {
+			t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+		}
+		_, stderr, err = tf.Run("apply")
+		if err != nil {
+			t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+		}
+
+		stdout, stderr, code, err := tf.RunExit("plan", "-detailed-exitcode", "-input=false")
+		if err != nil {
+			t.Fatalf("unexpected error running plan: %s\nstderr:\n%s", err, stderr)
+		}
+		if code != 0 {
+			t.Errorf("wrong exit code %d after apply; want 0\nstdout:\n%s\nstderr:\n%s", code, stdout, stderr)
+		}
+
+		config := `
+resource "test_resource" "foo" {
+  required = "changed"
+
+  required_map = {
+    key = "value"
+  }
+}
+`
+		if err := tf.WriteFile("main.tf", []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, code, err = tf.RunExit("plan", "-detailed-exitcode", "-input=false")
+		if err != nil {
+			t.Fatalf("unexpected error running plan: %s\nstderr:\n%s", err, stderr)
+		}
+		if code != 2 {
+			t.Errorf("wrong exit code %d after changing configuration; want 2\nstdout:\n%s\nstderr:\n%s", code, stdout, stderr)
+		}
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		tf := newTerraformWithMirror("test-provider", testPluginsDir)
+		defer tf.Close()
+
+		_, stderr, err := tf.Run("init")
+		if err != nil {
+			t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+		}
+		broken := `output "bad" { value = "${var.undeclared}" }`
+		if err := tf.WriteFile("broken.tf", []byte(broken), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		stdout, stderr, code, err := tf.RunExit("plan", "-detailed-exitcode", "-input=false")
+		if err != nil {
+			t.Fatalf("unexpected error running plan: %s\nstderr:\n%s", err, stderr)
+		}
+		if code != 1 {
+			t.Errorf("wrong exit code %d; want 1\nstdout:\n%s\nstderr:\n%s", code, stdout, stderr)
+		}
+		if !strings.Contains(stderr, "unknown variable referenced: 'undeclared'") {
+			t.Errorf("error does not mention the invalid reference:\n%s", stderr)
+		}
+	})
+}
+
+func TestPlanFilesWritten(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	t.Run("success", func(t *testing.T) {
+		tf := newTerraformWithMirror("test-provider", testPluginsDir)
+		defer tf.Close()
+
+		_, stderr, err := tf.Run("init")
+		if err != nil {
+			t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+		}
+
+		before, err := tf.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, stderr, err = tf.Run("plan", "-out=tfplan")
+		if err != nil {
+			t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+		}
+
+		// Only the requested plan file may be written. In particular, the
+		// refreshed state must not be persisted, and the state lock must
+		// have been cleaned up.
+		added, modified, removed := tf.DiffSnapshot(before)
+		if want := []string{"tfplan"}; !reflect.DeepEqual(added, want) {
+			t.Errorf("wrong files added\ngot:  %#v\nwant: %#v", added, want)
+		}
+		if len(modified) != 0 {
+			t.Errorf("unexpected files modified: %#v", modified)
+		}
+		if len(removed) != 0 {
+			t.Errorf("unexpected files removed: %#v", removed)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tf := newTerraformWithMirror("test-provider", testPluginsDir)
+		defer tf.Close()
+
+		_, stderr, err := tf.Run("init")
+		if err != nil {
+			t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+		}
+
+		// The output refers to a variable that is not declared, so the
+		// configuration loads but plan fails to validate it.
+		broken := `output "bad" { value = "${var.undeclared}" }`
+		if err := tf.WriteFile("broken.tf", []byte(broken), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		before, err := tf.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, code, err := tf.RunExit("plan", "-out=tfplan")
+		if err != nil {
+			t.Fatalf("unexpected error running plan: %s", err)
+		}
+		if code != 1 {
+			t.Fatalf("wrong exit code %d; want 1", code)
+		}
+
+		added, modified, removed := tf.DiffSnapshot(before)
+		if len(added) != 0 || len(modified) != 0 || len(removed) != 0 {
+			t.Errorf("failed plan changed the working directory\nadded:    %#v\nmodified: %#v\nremoved:  %#v", added, modified, removed)
+		}
+		if tf.FileExists("terraform.tfstate") {
+			t.Errorf("failed plan created a state file")
+		}
+	})
+}
+
+func TestPlanStale(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("count", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply", "-var", "instances=1")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// PLAN
+	_, stderr, err = tf.Run("plan", "-var", "instances=2", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// CHANGE STATE
+	// A different change applied after the plan was saved makes the state
+	// in the plan out of date.
+	_, stderr, err = tf.Run("apply", "-var", "instances=3")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// APPLY STALE PLAN
+	_, stderr, err = tf.Run("apply", "tfplan")
+	if err == nil {
+		t.Fatalf("apply succeeded with a stale plan")
+	}
+	if !strings.Contains(stderr, "This plan was created against an older state than is current") {
+		t.Errorf("wrong error for stale plan:\n%s", stderr)
+	}
+
+	addrs, err := tf.StateList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 3 {
+		t.Errorf("state was changed by the rejected plan: %#v", addrs)
+	}
+}
+
+func TestPlanDeterministic(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// Saved plan files are gob-encoded, which writes maps in iteration
+	// order, so two plan files for the same change are not byte-identical.
+	// The change they describe must be, though, and so must the plan as
+	// rendered by "terraform show".
+
+	tf := newTerraformWithMirror("deterministic-plan", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	// Start from a non-empty state, so the plans include updates and
+	// destroys as well as creates.
+	_, stderr, err = tf.Run("apply", "-var", "instances=2")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("taint", "test_resource.foo.1")
+	if err != nil {
+		t.Fatalf("unexpected taint error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	var shown []string
+	var plans []*tfcore.Plan
+	for _, name := range []string{"first.tfplan", "second.tfplan"} {
+		_, stderr, err := tf.Run("plan", "-out="+name)
+		if err != nil {
+			t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+		}
+		stdout, stderr, err := tf.RunPlain("show", name)
+		if err != nil {
+			t.Fatalf("unexpected show error: %s\nstderr:\n%s", err, stderr)
+		}
+		shown = append(shown, stdout)
+
+		plan, err := tf.Plan(name)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", name, err)
+		}
+		plans = append(plans, plan)
+	}
+
+	if shown[0] != shown[1] {
+		t.Errorf("show output differs between plans\nfirst:\n%s\nsecond:\n%s", shown[0], shown[1])
+	}
+	if !reflect.DeepEqual(plans[0].Diff, plans[1].Diff) {
+		t.Errorf("diff differs between plans\nfirst:\n%s\nsecond:\n%s", plans[0].Diff, plans[1].Diff)
+	}
+	if !plans[0].State.Equal(plans[1].State) {
+		t.Errorf("state differs between plans")
+	}
+}
+
+func TestPlanStreamRender(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// The stream-plan-render experiment writes out each resource in the
+	// plan as soon as it is formatted, but the output must be exactly the
+	// same as when the whole plan is formatted first.
+
+	tf := newTerraformWithMirror("large-plan", testPluginsDir)
+	defer tf.Close()
+	tf.StripColor = false
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	for _, extra := range [][]string{nil, {"-no-color"}} {
+		args := append([]string{"plan"}, extra...)
+		buffered, stderr, err := tf.Run(args...)
+		if err != nil {
+			t.Fatalf("unexpected %s error: %s\nstderr:\n%s", strings.Join(args, " "), err, stderr)
+		}
+		env := []string{"TF_X_STREAM_PLAN_RENDER=1"}
+		streamed, stderr, err := tf.RunWithEnv(env, args...)
+		if err != nil {
+			t.Fatalf("unexpected streamed %s error: %s\nstderr:\n%s", strings.Join(args, " "), err, stderr)
+		}
+
+		if !strings.Contains(buffered, "700 to add, 0 to change, 0 to destroy.") {
+			t.Fatalf("wrong plan from %s:\n%s", strings.Join(args, " "), buffered)
+		}
+		if streamed != buffered {
+			t.Errorf("streamed output from %s differs\nbuffered:\n%s\nstreamed:\n%s", strings.Join(args, " "), buffered, streamed)
+		}
+	}
+}
+
+func TestPlanDuration(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// A plan of this many resources takes well under a second, so it only
+	// goes over the budget if refreshing or diffing them gets a lot slower.
+
+	tf := newTerraformWithMirror("refresh-many", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	stdout, stderr, d, err := tf.RunTimed("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "No changes") {
+		t.Errorf("wrong plan after apply:\n%s", stdout)
+	}
+	assertUnder(t, d, 10*time.Second)
+}
@@ -0,0 +1,83 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanVersionMismatch(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	_, stderr, err = tf.Run("plan", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	orig, err := tf.ReadFile("tfplan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("terraform version", func(t *testing.T) {
+		plan, err := tf.Plan("tfplan")
+		if err != nil {
+			t.Fatalf("failed to read plan file: %s", err)
+		}
+		plan.TerraformVersion = "0.1.0"
+		if err := tf.SetPlan("other-version.tfplan", plan); err != nil {
+			t.Fatal(err)
+		}
+
+		_, stderr, err := tf.Run("apply", "other-version.tfplan")
+		if err == nil {
+			t.Fatalf("apply succeeded with a plan from a different version")
+		}
+		if !strings.Contains(stderr, "plan was created with a different version of Terraform") {
+			t.Errorf("error does not explain the version mismatch:\n%s", stderr)
+		}
+	})
+
+	t.Run("file format version", func(t *testing.T) {
+		src := append([]byte(nil), orig...)
+		src[len(planFileMagic)] = 99
+		if err := tf.WriteFile("other-format.tfplan", src, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := tf.Plan("other-format.tfplan")
+		if err, ok := err.(*planVersionMismatchError); !ok {
+			t.Errorf("wrong error reading plan: %#v; want *planVersionMismatchError", err)
+		} else if err.Version != 99 {
+			t.Errorf("wrong version in error %d; want 99", err.Version)
+		}
+
+		_, stderr, err = tf.Run("apply", "other-format.tfplan")
+		if err == nil {
+			t.Fatalf("apply succeeded with a plan in a different format")
+		}
+		if !strings.Contains(stderr, "unknown plan file version: 99") {
+			t.Errorf("error does not explain the version mismatch:\n%s", stderr)
+		}
+	})
+
+	// Neither of the rejected plans may have been applied.
+	if tf.FileExists("terraform.tfstate") {
+		state, err := tf.LocalState()
+		if err != nil {
+			t.Fatalf("failed to read state file: %s", err)
+		}
+		if len(state.RootModule().Resources) != 0 {
+			t.Errorf("a rejected plan was applied")
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package e2etest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPluginLockVerification(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// This version of Terraform records the SHA256 digest of each selected
+	// provider plugin in .terraform/plugins/OS_ARCH/lock.json during init,
+	// and other commands refuse to use a plugin that doesn't match.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	osArch := runtime.GOOS + "_" + runtime.GOARCH
+	lockFile := filepath.Join(".terraform", "plugins", osArch, "lock.json")
+	pluginFile := filepath.Join("terraform.d", "plugins", osArch, "terraform-provider-test"+exeSuffix())
+
+	lockedDigest := func() string {
+		hashes, err := tf.ProviderHashes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hashes["test"]) != 1 {
+			t.Fatalf("wrong digests recorded for the test provider: %#v", hashes["test"])
+		}
+		return hashes["test"][0]
+	}
+	pluginDigest := func() string {
+		return fileDigest(t, tf, pluginFile)
+	}
+	assertRejected := func() {
+		stdout, stderr, err := tf.RunPlain("plan")
+		if err == nil {
+			t.Fatalf("plan succeeded with a plugin that doesn't match the lock file")
+		}
+		// The details of the problem are printed as part of the normal
+		// output, with only a summary on stderr.
+		if !strings.Contains(stdout, "provider.test: new or changed plugin executable") {
+			t.Errorf("output does not explain the plugin mismatch:\n%s\nstderr:\n%s", stdout, stderr)
+		}
+	}
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	if got, want := lockedDigest(), pluginDigest(); got != want {
+		t.Fatalf("wrong digest recorded for the test provider\ngot:  %s\nwant: %s", got, want)
+	}
+
+	//// TAMPERED LOCK FILE
+	tampered := map[string]string{"test": strings.Repeat("0", sha256.Size*2)}
+	src, err := json.Marshal(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.WriteFile(lockFile, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertRejected()
+
+	// Re-running init with -upgrade selects the plugins afresh and so
+	// legitimately records their current digests.
+	_, stderr, err = tf.Run("init", "-upgrade")
+	if err != nil {
+		t.Fatalf("unexpected init -upgrade error: %s\nstderr:\n%s", err, stderr)
+	}
+	if got, want := lockedDigest(), pluginDigest(); got != want {
+		t.Errorf("init -upgrade did not record the plugin's digest\ngot:  %s\nwant: %s", got, want)
+	}
+	_, stderr, err = tf.Run("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error after init -upgrade: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// TAMPERED PLUGIN
+	f, err := os.OpenFile(tf.Path(pluginFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte("tampered"))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatalf("failed to modify plugin: %s", err)
+	}
+	assertRejected()
+}
+
+// fileDigest returns the SHA256 digest of the given file in the working
+// directory, in the hex form used in plugin lock files.
+func fileDigest(t *testing.T, tf *terraform, path string) string {
+	src, err := tf.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(src))
+}
+
+func TestProviderHashes(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// Each platform has its own lock file, so a working directory that is
+	// shared between platforms has a digest for each of them.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	osArch := runtime.GOOS + "_" + runtime.GOARCH
+	pluginFile := filepath.Join("terraform.d", "plugins", osArch, "terraform-provider-test"+exeSuffix())
+
+	hashes, err := tf.ProviderHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 0 {
+		t.Fatalf("digests recorded before init: %#v", hashes)
+	}
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	first := fileDigest(t, tf, pluginFile)
+	hashes, err = tf.ProviderHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{"test": {first}}; !reflect.DeepEqual(hashes, want) {
+		t.Fatalf("wrong digests after init\ngot:  %#v\nwant: %#v", hashes, want)
+	}
+
+	//// UPGRADE
+	// A new build of the plugin replaces the digest for this platform
+	// rather than adding to it.
+	f, err := os.OpenFile(tf.Path(pluginFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte("rebuilt"))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatalf("failed to modify plugin: %s", err)
+	}
+	_, stderr, err = tf.Run("init", "-upgrade")
+	if err != nil {
+		t.Fatalf("unexpected init -upgrade error: %s\nstderr:\n%s", err, stderr)
+	}
+	second := fileDigest(t, tf, pluginFile)
+	hashes, err = tf.ProviderHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{"test": {second}}; !reflect.DeepEqual(hashes, want) {
+		t.Fatalf("wrong digests after init -upgrade\ngot:  %#v\nwant: %#v", hashes, want)
+	}
+
+	//// OTHER PLATFORM
+	// We can't run init for another platform, so its lock file is written
+	// as init would have written it there.
+	other := strings.Repeat("ab", sha256.Size)
+	src, err := json.Marshal(map[string]string{"test": other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLockFile := filepath.Join(".terraform", "plugins", "otheros_otherarch", "lock.json")
+	if err := tf.WriteFile(otherLockFile, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	hashes, err = tf.ProviderHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{second, other}
+	sort.Strings(want)
+	if !reflect.DeepEqual(hashes["test"], want) {
+		t.Errorf("wrong digests with a second platform\ngot:  %#v\nwant: %#v", hashes["test"], want)
+	}
+}
+
+// severalProviders are the names of the providers in the "several-providers"
+// fixture, which newTerraformWithSeveralProviders installs as copies of the
+// "test" provider.
+var severalProviders = []string{"alpha", "beta", "delta", "gamma"}
+
+// newTerraformWithSeveralProviders returns a harness for the
+// "several-providers" fixture with each of severalProviders installed.
+func newTerraformWithSeveralProviders() (*terraform, error) {
+	tf := newTerraformWithMirror("several-providers", testPluginsDir)
+	pluginDir := tf.Path("terraform.d", "plugins", runtime.GOOS+"_"+runtime.GOARCH)
+	for _, name := range severalProviders {
+		err := copyFile(
+			filepath.Join(pluginDir, "terraform-provider-"+name+exeSuffix()),
+			filepath.Join(testPluginsDir, "terraform-provider-test"+exeSuffix()),
+		)
+		if err != nil {
+			tf.Close()
+			return nil, err
+		}
+	}
+	return tf, nil
+}
+
+func TestPluginLockSeveralProviders(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// A plugin that can't be read must be reported on its own, by name,
+	// without hiding the others.
+
+	tf, err := newTerraformWithSeveralProviders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.Close()
+
+	osArch := runtime.GOOS + "_" + runtime.GOARCH
+	brokenPlugin := filepath.Join("terraform.d", "plugins", osArch, "terraform-provider-beta"+exeSuffix())
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	hashes, err := tf.ProviderHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range severalProviders {
+		if len(hashes[name]) != 1 {
+			t.Errorf("wrong digests recorded for provider %q: %#v", name, hashes[name])
+		}
+	}
+
+	stdout, stderr, err := tf.Run("plan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "4 to add, 0 to change, 0 to destroy.") {
+		t.Errorf("wrong plan output:\n%s", stdout)
+	}
+
+	//// UNREADABLE PLUGIN
+	// A directory is found as a plugin, but can't be read as one.
+	if err := os.Remove(tf.Path(brokenPlugin)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(tf.Path(brokenPlugin), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err = tf.RunPlain("plan")
+	if err == nil {
+		t.Fatalf("plan succeeded with an unreadable plugin")
+	}
+	if !strings.Contains(stdout, "provider.beta: failed to load plugin to verify its signature") {
+		t.Errorf("plan output does not explain the problem with provider.beta:\n%s\nstderr:\n%s", stdout, stderr)
+	}
+	for _, name := range []string{"alpha", "delta", "gamma"} {
+		if strings.Contains(stdout, "provider."+name+":") {
+			t.Errorf("plan output reports a problem with provider.%s:\n%s", name, stdout)
+		}
+	}
+
+	_, stderr, err = tf.RunPlain("init")
+	if err == nil {
+		t.Fatalf("init succeeded with an unreadable plugin")
+	}
+	if !strings.Contains(stderr, `failed to read plugin for provider "beta"`) {
+		t.Errorf("init errors do not explain the problem with provider beta:\n%s", stderr)
+	}
+	for _, name := range []string{"alpha", "delta", "gamma"} {
+		if strings.Contains(stderr, fmt.Sprintf("provider %q", name)) {
+			t.Errorf("init errors report a problem with provider %q:\n%s", name, stderr)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	tfcore "github.com/hashicorp/terraform/terraform"
 )
 
 // The tests in this file are for the "primary workflow", which includes
@@ -19,12 +20,10 @@ import (
 func TestPrimarySeparatePlan(t *testing.T) {
 	t.Parallel()
 
-	// This test reaches out to releases.hashicorp.com to download the
-	// template and null providers, so it can only run if network access is
-	// allowed.
-	skipIfCannotAccessNetwork(t)
-
-	tf := newTerraform("full-workflow-null")
+	// This test needs the template and null providers, which it downloads
+	// from releases.hashicorp.com unless they are pre-staged on the local
+	// filesystem. See newTerraformForProviders.
+	tf := newTerraformForProviders(t, "full-workflow-null")
 	defer tf.Close()
 
 	//// INIT
@@ -34,14 +33,20 @@ func TestPrimarySeparatePlan(t *testing.T) {
 	}
 
 	// Make sure we actually downloaded the plugins, rather than picking up
-	// copies that might be already installed globally on the system.
-	if !strings.Contains(stdout, "- Downloading plugin for provider \"template\"") {
-		t.Errorf("template provider download message is missing from init output:\n%s", stdout)
-		t.Logf("(this can happen if you have a copy of the plugin in one of the global plugin search dirs)")
-	}
-	if !strings.Contains(stdout, "- Downloading plugin for provider \"null\"") {
-		t.Errorf("null provider download message is missing from init output:\n%s", stdout)
-		t.Logf("(this can happen if you have a copy of the plugin in one of the global plugin search dirs)")
+	// copies that might be already installed globally on the system. There
+	// is nothing to download if we're using a local mirror, in which case
+	// we make sure the mirrored copies were used instead.
+	if tf.expectDownloads {
+		if !strings.Contains(stdout, "- Downloading plugin for provider \"template\"") {
+			t.Errorf("template provider download message is missing from init output:\n%s", stdout)
+			t.Logf("(this can happen if you have a copy of the plugin in one of the global plugin search dirs)")
+		}
+		if !strings.Contains(stdout, "- Downloading plugin for provider \"null\"") {
+			t.Errorf("null provider download message is missing from init output:\n%s", stdout)
+			t.Logf("(this can happen if you have a copy of the plugin in one of the global plugin search dirs)")
+		}
+	} else if strings.Contains(stdout, "- Downloading plugin for provider") {
+		t.Errorf("init downloaded a plugin that should've been found in the local mirror:\n%s", stdout)
 	}
 
 	//// PLAN
@@ -59,6 +64,8 @@ func TestPrimarySeparatePlan(t *testing.T) {
 		t.Fatalf("failed to read plan file: %s", err)
 	}
 
+	assertPlanTally(t, plan, 1, 0, 0)
+
 	stateResources := plan.State.RootModule().Resources
 	diffResources := plan.Diff.RootModule().Resources
 
@@ -84,6 +91,8 @@ func TestPrimarySeparatePlan(t *testing.T) {
 		t.Fatalf("failed to read state file: %s", err)
 	}
 
+	assertApplyTally(t, plan.State, state, 1, 0, 0)
+
 	stateResources = state.RootModule().Resources
 	var gotResources []string
 	for n := range stateResources {
@@ -100,6 +109,8 @@ func TestPrimarySeparatePlan(t *testing.T) {
 		t.Errorf("wrong resources in state\ngot: %#v\nwant: %#v", gotResources, wantResources)
 	}
 
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+
 	//// DESTROY
 	stdout, stderr, err = tf.Run("destroy", "-force")
 	if err != nil {
@@ -110,14 +121,204 @@ func TestPrimarySeparatePlan(t *testing.T) {
 		t.Errorf("incorrect destroy tally; want 2 destroyed:\n%s", stdout)
 	}
 
+	prevState := state
 	state, err = tf.LocalState()
 	if err != nil {
 		t.Fatalf("failed to read state file after destroy: %s", err)
 	}
 
+	assertApplyTally(t, prevState, state, 0, 0, 2)
+
 	stateResources = state.RootModule().Resources
 	if len(stateResources) != 0 {
 		t.Errorf("wrong resources in state after destroy; want none, but still have:%s", spew.Sdump(stateResources))
 	}
 
 }
+
+func TestPrimaryAutoApprove(t *testing.T) {
+	t.Parallel()
+
+	// This test needs the template and null providers, which it downloads
+	// from releases.hashicorp.com unless they are pre-staged on the local
+	// filesystem. See newTerraformForProviders.
+	tf := newTerraformForProviders(t, "full-workflow-null")
+	defer tf.Close()
+
+	//// INIT
+	stdout, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// APPLY
+	// This is the single-step variant of TestPrimarySeparatePlan, where
+	// the plan is created and applied in the same command without being
+	// saved to disk.
+	stdout, stderr, err = tf.Run("apply", "-auto-approve")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	if !strings.Contains(stdout, "Resources: 1 added, 0 changed, 0 destroyed") {
+		t.Errorf("incorrect apply tally; want 1 added:\n%s", stdout)
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+
+	stateResources := state.RootModule().Resources
+	var gotResources []string
+	for n := range stateResources {
+		gotResources = append(gotResources, n)
+	}
+	sort.Strings(gotResources)
+
+	wantResources := []string{
+		"data.template_file.test",
+		"null_resource.test",
+	}
+
+	if !reflect.DeepEqual(gotResources, wantResources) {
+		t.Errorf("wrong resources in state\ngot: %#v\nwant: %#v", gotResources, wantResources)
+	}
+
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+}
+
+func TestPrimaryTargeted(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("targeted", testPluginsDir)
+	defer tf.Close()
+
+	//// INIT
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// PLAN
+	stdout, stderr, err := tf.Run("plan", "-target=test_resource.a", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	plan, err := tf.Plan("tfplan")
+	if err != nil {
+		t.Fatalf("failed to read plan file: %s", err)
+	}
+
+	// The targeted resource's dependency must be included too, but the
+	// unrelated resource must not.
+	gotTargets := planTargets(plan)
+	wantTargets := []string{
+		"test_resource.a",
+		"test_resource.dep",
+	}
+	if !reflect.DeepEqual(gotTargets, wantTargets) {
+		t.Errorf("wrong resources in plan\ngot:  %#v\nwant: %#v\n\n%s", gotTargets, wantTargets, stdout)
+	}
+
+	//// APPLY
+	stdout, stderr, err = tf.Run("apply", "tfplan")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	if !strings.Contains(stdout, "Resources: 2 added, 0 changed, 0 destroyed") {
+		t.Errorf("incorrect apply tally; want 2 added:\n%s", stdout)
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+
+	stateResources := state.RootModule().Resources
+	var gotResources []string
+	for n := range stateResources {
+		gotResources = append(gotResources, n)
+	}
+	sort.Strings(gotResources)
+
+	if !reflect.DeepEqual(gotResources, wantTargets) {
+		t.Errorf("wrong resources in state\ngot:  %#v\nwant: %#v", gotResources, wantTargets)
+	}
+	assertNotInState(t, tf, "test_resource.other")
+}
+
+func TestPrimaryDestroyPlan(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("destroy-plan", testPluginsDir)
+	defer tf.Close()
+
+	//// INIT
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// APPLY
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+
+	//// PLAN
+	stdout, stderr, err := tf.Run("plan", "-destroy", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	plan, err := tf.Plan("tfplan")
+	if err != nil {
+		t.Fatalf("failed to read plan file: %s", err)
+	}
+
+	for _, mod := range plan.Diff.Modules {
+		for key, rd := range mod.Resources {
+			if ct := rd.ChangeType(); ct != tfcore.DiffDestroy {
+				t.Errorf("%s has change type %#v; want DiffDestroy", key, ct)
+			}
+		}
+	}
+	assertPlanTally(t, plan, 0, 0, 2)
+	scanPlanFilesForSecrets(tf, t, defaultSecretPatterns())
+
+	//// APPLY
+	stdout, stderr, err = tf.Run("apply", "tfplan")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// Applying a saved destroy plan reports the normal apply summary rather
+	// than the one produced by "terraform destroy".
+	if !strings.Contains(stdout, "Resources: 0 added, 0 changed, 2 destroyed") {
+		t.Errorf("incorrect apply tally; want 2 destroyed:\n%s", stdout)
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+
+	if len(state.RootModule().Resources) > 0 {
+		t.Errorf("wrong resources in state after destroy; want none\n%s", spew.Sdump(state.RootModule().Resources))
+	}
+
+	// The backup file written during the destroy holds the previous state,
+	// so it must be checked as well as the now-empty state file.
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+}
@@ -0,0 +1,111 @@
+package e2etest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPrivateModuleNetrc(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Terraform on Windows doesn't find its home directory from the environment")
+	}
+
+	// This test uses the "test" provider from our own build, and a stub
+	// module server on the loopback interface, so it can run without
+	// network access.
+	//
+	// This version of Terraform has no credentials blocks in its CLI
+	// configuration. Modules from HTTP sources that require authentication
+	// instead get their credentials from the .netrc file in the user's home
+	// directory, which is added to the request as basic auth.
+
+	tf := newTerraformWithMirror("private-module", testPluginsDir)
+	defer tf.Close()
+	home := tf.withFakeHome(t)
+
+	const login = "e2e"
+	const password = "netrc-" + secretMarker
+	childSource := "file://" + filepath.ToSlash(tf.Path("modules", "child"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != login || pass != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Terraform-Get", childSource)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := tf.ReadFile("main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := strings.Replace(string(src), `"MODULE_URL"`, `"`+server.URL+`/child"`, 1)
+	if err := tf.WriteFile("main.tf", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//// INIT WITHOUT CREDENTIALS
+	_, stderr, err := tf.Run("init")
+	if err == nil {
+		t.Fatalf("init succeeded without credentials for the module server")
+	}
+	if !strings.Contains(stderr, "bad response code: 401") {
+		t.Errorf("wrong error without credentials:\n%s", stderr)
+	}
+
+	//// INIT
+	netrc := fmt.Sprintf("machine %s\nlogin %s\npassword %s\n", serverURL.Host, login, password)
+	if err := ioutil.WriteFile(filepath.Join(home, ".netrc"), []byte(netrc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(home, "terraform.log")
+	env := []string{"TF_LOG=TRACE", "TF_LOG_PATH=" + logPath}
+	stdout, stderr, err := tf.RunWithEnv(env, "init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Get: "+server.URL+"/child") {
+		t.Errorf("module installation message is missing from init output:\n%s", stdout)
+	}
+
+	//// APPLY
+	stdout, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	outputs, err := tf.Outputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output := outputs["child_greeting"]; output == nil || output.Value != "hello" {
+		t.Errorf("wrong value for output \"child_greeting\": %#v", output)
+	}
+
+	//// NO LEAKS
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+	log, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := regexp.MustCompile(regexp.QuoteMeta(password))
+	for name, content := range map[string]string{"init log": string(log), "apply output": stdout} {
+		if secret.MatchString(content) {
+			t.Errorf("password from .netrc appears in the %s", name)
+		}
+	}
+}
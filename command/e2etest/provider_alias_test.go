@@ -0,0 +1,65 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProviderAlias(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Each test_provider_label data source reports
+	// the label of the provider configuration that read it.
+
+	tf := newTerraformWithMirror("provider-alias", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	outputs, err := tf.Outputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"default_label": "default", "west_label": "west"} {
+		if got := outputs[name]; got == nil || got.Value != want {
+			t.Errorf("wrong value for output %q: %#v; want %q", name, got, want)
+		}
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	rs := state.RootModule().Resources["test_resource.west"]
+	if rs == nil {
+		t.Fatalf("test_resource.west is missing from state")
+	}
+	if got, want := rs.Provider, "test.west"; got != want {
+		t.Errorf("wrong provider for test_resource.west %q; want %q", got, want)
+	}
+
+	//// UNDEFINED ALIAS
+	src, err := tf.ReadFile("main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := strings.Replace(string(src), `provider = "test.west"`, `provider = "test.east"`, -1)
+	if err := tf.WriteFile("main.tf", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = tf.Run("plan")
+	if err == nil {
+		t.Fatalf("plan succeeded with an undefined provider alias")
+	}
+	if !strings.Contains(stderr, "provider alias must be defined by the module or a parent: test.east") {
+		t.Errorf("wrong error for undefined provider alias:\n%s", stderr)
+	}
+}
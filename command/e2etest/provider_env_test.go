@@ -0,0 +1,62 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProviderConfigFromEnv(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. The provider takes its label from the
+	// TEST_PROVIDER_LABEL environment variable when it isn't set in
+	// configuration, and test_resource.foo records whichever label the
+	// provider was configured with.
+
+	tf := newTerraformWithMirror("provider-env", testPluginsDir)
+	defer tf.Close()
+
+	env := []string{"TEST_PROVIDER_LABEL=from-env"}
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// FROM ENVIRONMENT
+	_, stderr, err = tf.RunWithEnv(env, "apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	got, err := tf.StateAttr("test_resource.foo", "required")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "from-env"; got != want {
+		t.Errorf("wrong label from environment %q; want %q", got, want)
+	}
+
+	//// EXPLICIT ATTRIBUTE
+	// An attribute set in configuration takes precedence over the
+	// environment variable.
+	src, err := tf.ReadFile("main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := strings.Replace(string(src), "provider \"test\" {\n", "provider \"test\" {\n  label = \"from-config\"\n", 1)
+	if err := tf.WriteFile("main.tf", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = tf.RunWithEnv(env, "apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	got, err = tf.StateAttr("test_resource.foo", "required")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "from-config"; got != want {
+		t.Errorf("wrong label with explicit attribute %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,320 @@
+package e2etest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	plugin "github.com/hashicorp/go-plugin"
+	testprovider "github.com/hashicorp/terraform/builtin/providers/test"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+// testProviderServers are the servers started by serveTestProvider, along
+// with the address of each keyed by the provider that it serves.
+var testProviderServers struct {
+	sync.Mutex
+	addrs     map[tfcore.ResourceProvider]net.Addr
+	listeners []net.Listener
+	dirs      []string
+}
+
+// serveTestProvider serves the given provider over the plugin protocol from
+// within the test process, and returns the configuration for reattaching
+// to it with ReattachProvider.
+//
+// The first call for a particular provider starts a server for it, which
+// is then shared by every later call for the same provider, including from
+// tests running in parallel. Each Terraform process that connects gets its
+// own connection, but all of them use the same provider value, so it must
+// be safe for concurrent use. The servers are stopped once all of the tests
+// have completed.
+func serveTestProvider(p tfcore.ResourceProvider) (*plugin.ReattachConfig, error) {
+	testProviderServers.Lock()
+	defer testProviderServers.Unlock()
+
+	if addr, ok := testProviderServers.addrs[p]; ok {
+		return testProviderReattachConfig(addr), nil
+	}
+
+	// Unix socket paths are limited to around a hundred bytes, so the
+	// socket goes in its own short-named temporary directory rather than
+	// in a test's working directory.
+	dir, err := ioutil.TempDir("", "tf-provider")
+	if err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "provider.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen for provider connections: %s", err)
+	}
+
+	server := &plugin.RPCServer{
+		Plugins: map[string]plugin.Plugin{
+			tfplugin.ProviderPluginName: &tfplugin.ResourceProviderPlugin{
+				F: func() tfcore.ResourceProvider { return p },
+			},
+		},
+		Stdout: new(bytes.Buffer),
+		Stderr: new(bytes.Buffer),
+	}
+	go server.Serve(l)
+
+	if testProviderServers.addrs == nil {
+		testProviderServers.addrs = make(map[tfcore.ResourceProvider]net.Addr)
+	}
+	testProviderServers.addrs[p] = l.Addr()
+	testProviderServers.listeners = append(testProviderServers.listeners, l)
+	testProviderServers.dirs = append(testProviderServers.dirs, dir)
+	return testProviderReattachConfig(l.Addr()), nil
+}
+
+// testProviderReattachConfig returns the configuration for reattaching to
+// a server started by serveTestProvider at the given address.
+func testProviderReattachConfig(addr net.Addr) *plugin.ReattachConfig {
+	return &plugin.ReattachConfig{
+		Protocol: plugin.ProtocolNetRPC,
+		Addr:     addr,
+		Pid:      os.Getpid(),
+	}
+}
+
+// closeTestProviderServers stops all of the servers started by
+// serveTestProvider.
+func closeTestProviderServers() {
+	testProviderServers.Lock()
+	defer testProviderServers.Unlock()
+
+	for _, l := range testProviderServers.listeners {
+		l.Close()
+	}
+	for _, dir := range testProviderServers.dirs {
+		os.RemoveAll(dir)
+	}
+	testProviderServers.addrs = nil
+	testProviderServers.listeners = nil
+	testProviderServers.dirs = nil
+}
+
+// newTerraformWithTestProvider is like newTerraform but arranges for the
+// "test" provider that the fixture requires to be the given provider,
+// served from within the test process by serveTestProvider.
+//
+// Terraform is told about the server with TF_REATTACH_PROVIDERS, so no
+// provider plugin process is started and "terraform init" has nothing to
+// download. Tests can also inspect the provider directly to see what
+// Terraform asked of it.
+//
+// As with newTerraform, this function will panic if the working directory
+// cannot be prepared or the server cannot be started.
+func newTerraformWithTestProvider(fixtureName string, p tfcore.ResourceProvider) *terraform {
+	reattach, err := serveTestProvider(p)
+	if err != nil {
+		panic(err)
+	}
+
+	tf := newTerraform(fixtureName)
+	tf.expectDownloads = false
+	if err := tf.ReattachProvider("test", reattach); err != nil {
+		panic(err)
+	}
+	return tf
+}
+
+// ReattachProvider arranges for every command that the harness runs from
+// then on to use the provider that is already running as described by the
+// given configuration, rather than starting a plugin for the named
+// provider itself. Calling it again with the same name replaces the
+// earlier configuration.
+//
+// The configuration can come from the ReattachConfig method of a go-plugin
+// client that has started a provider, as with startTestProviderPlugin, or
+// from serveTestProvider. Terraform is told about the providers with the
+// TF_REATTACH_PROVIDERS environment variable, in the same form that
+// a provider running under a debugger would print for it.
+func (t *terraform) ReattachProvider(name string, c *plugin.ReattachConfig) error {
+	if c == nil || c.Addr == nil {
+		return fmt.Errorf("provider %q is not running", name)
+	}
+	if t.reattached == nil {
+		t.reattached = make(map[string]*plugin.ReattachConfig)
+	}
+	t.reattached[name] = c
+
+	reattach := make(map[string]interface{}, len(t.reattached))
+	for providerName, rc := range t.reattached {
+		// This version of go-plugin has no Test field in ReattachConfig, so
+		// only the fields that the configuration actually holds are given.
+		reattach[providerName] = map[string]interface{}{
+			"Protocol": rc.Protocol,
+			"Pid":      rc.Pid,
+			"Addr": map[string]string{
+				"Network": rc.Addr.Network(),
+				"String":  rc.Addr.String(),
+			},
+		}
+	}
+	src, err := json.Marshal(reattach)
+	if err != nil {
+		return err
+	}
+
+	// The new setting comes last, so mergeEnv gives it precedence over
+	// any earlier one.
+	t.env = append(t.env, "TF_REATTACH_PROVIDERS="+string(src))
+	return nil
+}
+
+// startTestProviderPlugin starts the "test" provider plugin from
+// testPluginsDir as a child of the test process, completing the plugin
+// handshake just as Terraform would, and returns the configuration for
+// reattaching to it with ReattachProvider. The given environment variables
+// are set for the plugin process in addition to those of the test process.
+//
+// The returned function stops the plugin, and callers should defer it.
+// The plugin's own logs are shown only when the tests are run verbosely.
+func startTestProviderPlugin(t *testing.T, env []string) (*plugin.ReattachConfig, func()) {
+	logOutput := ioutil.Discard
+	if testing.Verbose() {
+		logOutput = os.Stderr
+	}
+
+	cmd := exec.Command(filepath.Join(testPluginsDir, "terraform-provider-test"+exeSuffix()))
+	cmd.Env = mergeEnv(os.Environ(), env)
+	client := plugin.NewClient(&plugin.ClientConfig{
+		Cmd:             cmd,
+		HandshakeConfig: tfplugin.Handshake,
+		Plugins:         tfplugin.PluginMap,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin",
+			Level:  hclog.Trace,
+			Output: logOutput,
+		}),
+	})
+
+	if _, err := client.Start(); err != nil {
+		client.Kill()
+		t.Fatalf("failed to start test provider plugin: %s", err)
+	}
+	return client.ReattachConfig(), client.Kill
+}
+
+// countingProvider is a provider that counts the number of times that Apply
+// is called, so that tests can tell that Terraform used it.
+type countingProvider struct {
+	tfcore.ResourceProvider
+	applies int64
+}
+
+func (p *countingProvider) Apply(info *tfcore.InstanceInfo, s *tfcore.InstanceState, d *tfcore.InstanceDiff) (*tfcore.InstanceState, error) {
+	atomic.AddInt64(&p.applies, 1)
+	return p.ResourceProvider.Apply(info, s, d)
+}
+
+func TestReattachedProvider(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, served from
+	// within the test process, so there is no plugin executable at all and
+	// it can run without network access.
+	//
+	// Both subtests share the same server, and each applies one resource.
+
+	p := &countingProvider{ResourceProvider: testprovider.Provider()}
+
+	t.Run("apply", func(t *testing.T) {
+		for _, name := range []string{"first", "second"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				tf := newTerraformWithTestProvider("test-provider", p)
+				defer tf.Close()
+
+				stdout, stderr, err := tf.Run("init")
+				if err != nil {
+					t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+				}
+				if strings.Contains(stdout, "Downloading plugin") {
+					t.Errorf("init downloaded a plugin for a reattached provider:\n%s", stdout)
+				}
+
+				stdout, stderr, err = tf.Run("plan")
+				if err != nil {
+					t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+				}
+				if !strings.Contains(stdout, "1 to add, 0 to change, 0 to destroy") {
+					t.Errorf("wrong plan output:\n%s", stdout)
+				}
+
+				_, stderr, err = tf.Run("apply")
+				if err != nil {
+					t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+				}
+				id, err := tf.StateAttr("test_resource.foo", "id")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id != "testId" {
+					t.Errorf("wrong id %q for test_resource.foo; want %q", id, "testId")
+				}
+			})
+		}
+	})
+
+	if got, want := atomic.LoadInt64(&p.applies), int64(2); got != want {
+		t.Errorf("reattached provider applied %d resources; want %d", got, want)
+	}
+}
+
+func TestReattachedProviderPlugin(t *testing.T) {
+	t.Parallel()
+
+	// This test starts the "test" provider plugin from our own build itself,
+	// so it can run without network access.
+	//
+	// The provider takes its label from the TEST_PROVIDER_LABEL environment
+	// variable, which is set only for the plugin process, and
+	// test_resource.foo records that label. Terraform can therefore only
+	// have applied it using the reattached plugin.
+
+	reattach, kill := startTestProviderPlugin(t, []string{"TEST_PROVIDER_LABEL=reattached"})
+	defer kill()
+
+	tf := newTerraform("provider-env")
+	defer tf.Close()
+	if err := tf.ReattachProvider("test", reattach); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	if strings.Contains(stdout, "Downloading plugin") {
+		t.Errorf("init downloaded a plugin for a reattached provider:\n%s", stdout)
+	}
+
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	got, err := tf.StateAttr("test_resource.foo", "required")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "reattached"; got != want {
+		t.Errorf("wrong label %q from reattached provider; want %q", got, want)
+	}
+}
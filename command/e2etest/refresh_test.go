@@ -0,0 +1,232 @@
+package e2etest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRefreshDrift(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// This version of Terraform has no -refresh-only planning mode, so we
+	// instead check how a normal plan, a plan with -refresh=false and the
+	// separate "refresh" command each deal with drift.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// The test provider always reports computed_read_only as
+	// "value_from_api", so changing it in the state simulates the remote
+	// object having drifted from what was last recorded.
+	const addr = "test_resource.foo"
+	const attr = "computed_read_only"
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	state.RootModule().Resources[addr].Primary.Attributes[attr] = "drifted"
+	if err := tf.SetLocalState(state); err != nil {
+		t.Fatalf("failed to write state file: %s", err)
+	}
+
+	t.Run("plan -refresh=false", func(t *testing.T) {
+		_, stderr, err := tf.Run("plan", "-refresh=false", "-out=stale.tfplan")
+		if err != nil {
+			t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+		}
+		plan, err := tf.Plan("stale.tfplan")
+		if err != nil {
+			t.Fatalf("failed to read plan file: %s", err)
+		}
+
+		// Without refreshing, the stale state is used as-is.
+		got := plan.State.RootModule().Resources[addr].Primary.Attributes[attr]
+		if got != "drifted" {
+			t.Errorf("wrong %s in plan's state %q; want the stale value", attr, got)
+		}
+		assertPlanTally(t, plan, 0, 0, 0)
+	})
+
+	t.Run("plan", func(t *testing.T) {
+		_, stderr, err := tf.Run("plan", "-out=refreshed.tfplan")
+		if err != nil {
+			t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+		}
+		plan, err := tf.Plan("refreshed.tfplan")
+		if err != nil {
+			t.Fatalf("failed to read plan file: %s", err)
+		}
+
+		// The refresh detects the drift, which is reflected only in the
+		// plan's state and does not cause any resource actions.
+		got := plan.State.RootModule().Resources[addr].Primary.Attributes[attr]
+		if got != "value_from_api" {
+			t.Errorf("wrong %s in plan's state %q; want the refreshed value", attr, got)
+		}
+		assertPlanTally(t, plan, 0, 0, 0)
+
+		// Planning doesn't persist the refreshed state, though.
+		got, err = tf.StateAttr(addr, attr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "drifted" {
+			t.Errorf("plan wrote %s = %q to the state file", attr, got)
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		_, stderr, err := tf.Run("refresh")
+		if err != nil {
+			t.Fatalf("unexpected refresh error: %s\nstderr:\n%s", err, stderr)
+		}
+		got, err := tf.StateAttr(addr, attr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "value_from_api" {
+			t.Errorf("wrong %s in state after refresh %q; want the refreshed value", attr, got)
+		}
+	})
+}
+
+func TestRefreshTargeted(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Each test_resource_reads instance in the
+	// fixture records its name in the "reads" file whenever the provider
+	// reads it. Only the configuration of "b" changes, and "b" depends on
+	// "a".
+
+	tf := newTerraformWithMirror("refresh-targeted", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	reads := func(args ...string) []string {
+		if err := os.Remove(tf.Path("reads")); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, err := tf.RunPlain(args...)
+		if err != nil {
+			t.Fatalf("unexpected %s error: %s\nstderr:\n%s", strings.Join(args, " "), err, stderr)
+		}
+		if !strings.Contains(stdout, "1 to change") {
+			t.Errorf("wrong plan from %s:\n%s", strings.Join(args, " "), stdout)
+		}
+
+		var names []string
+		if src, err := tf.ReadFile("reads"); err == nil {
+			names = strings.Fields(string(src))
+		} else if !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	full := reads("plan", "-var", "b_value=two")
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(full, want) {
+		t.Errorf("wrong reads from a full refresh %#v; want %#v", full, want)
+	}
+
+	targeted := reads("plan", "-refresh=targeted", "-var", "b_value=two")
+	if want := []string{"a", "b"}; !reflect.DeepEqual(targeted, want) {
+		t.Errorf("wrong reads from a targeted refresh %#v; want %#v", targeted, want)
+	}
+
+	if len(targeted) >= len(full) {
+		t.Errorf("targeted refresh made %d reads, but a full refresh made only %d", len(targeted), len(full))
+	}
+}
+
+func TestRefreshDeterministic(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// Refresh reads many resources at the same time, but the state that it
+	// writes must not depend on the order in which those reads finish.
+	// Otherwise two refreshes with nothing changed would give different
+	// state files, and any diff between them would be noise.
+
+	tf := newTerraformWithMirror("refresh-many", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	var first []byte
+	for i := 0; i < 5; i++ {
+		_, stderr, err = tf.Run("refresh")
+		if err != nil {
+			t.Fatalf("unexpected refresh error: %s\nstderr:\n%s", err, stderr)
+		}
+		src, err := tf.ReadFile("terraform.tfstate")
+		if err != nil {
+			t.Fatalf("failed to read state file: %s", err)
+		}
+
+		// Refresh only increments the serial if the state changed, but
+		// that is covered by TestStateSerial, so it is ignored here.
+		src = stateSerialRegexp.ReplaceAll(src, []byte(`"serial": 0,`))
+
+		if first == nil {
+			first = src
+			continue
+		}
+		if !bytes.Equal(src, first) {
+			t.Fatalf("state after refresh %d differs from the first: %s", i+1, firstLineDifference(string(first), string(src)))
+		}
+	}
+}
+
+// stateSerialRegexp matches the serial in a state file written by
+// WriteState.
+var stateSerialRegexp = regexp.MustCompile(`(?m)^    "serial": \d+,$`)
+
+// firstLineDifference describes the first line at which a and b differ,
+// for tests that compare outputs too long to show in full.
+func firstLineDifference(a, b string) string {
+	aLines := strings.Split(a, "\n")
+	bLines := strings.Split(b, "\n")
+	for i := 0; i < len(aLines) && i < len(bLines); i++ {
+		if aLines[i] != bLines[i] {
+			return fmt.Sprintf("line %d is %q; was %q", i+1, bLines[i], aLines[i])
+		}
+	}
+	return fmt.Sprintf("%d lines; was %d lines", len(bLines), len(aLines))
+}
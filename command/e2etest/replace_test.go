@@ -0,0 +1,146 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. Both resources in the fixture set a ForceNew
+	// attribute from the same variable, but only "cbd" sets
+	// create_before_destroy.
+
+	tf := newTerraformWithMirror("replace", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// PLAN
+	_, stderr, err = tf.Run("plan", "-var", "generation=2", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	plan, err := tf.Plan("tfplan")
+	if err != nil {
+		t.Fatalf("failed to read plan file: %s", err)
+	}
+	assertPlanTally(t, plan, 2, 0, 2)
+
+	wantModes := map[string]string{
+		"test_resource.default": "DeleteThenCreate",
+		"test_resource.cbd":     "CreateThenDelete",
+	}
+	for addr, want := range wantModes {
+		if got := replacementMode(plan, addr); got != want {
+			t.Errorf("wrong replacement mode for %s %q; want %q", addr, got, want)
+		}
+	}
+
+	//// APPLY
+	stdout, stderr, err := tf.Run("apply", "tfplan")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// The two resources are replaced concurrently, so their messages may be
+	// interleaved, but each one's own messages must be in the right order.
+	assertBefore := func(first, second string) {
+		i, j := strings.Index(stdout, first), strings.Index(stdout, second)
+		switch {
+		case i < 0:
+			t.Errorf("apply output does not include %q:\n%s", first, stdout)
+		case j < 0:
+			t.Errorf("apply output does not include %q:\n%s", second, stdout)
+		case i > j:
+			t.Errorf("%q came after %q in apply output:\n%s", first, second, stdout)
+		}
+	}
+	assertBefore("test_resource.default: Destroying...", "test_resource.default: Creating...")
+	assertBefore("test_resource.cbd: Creating...", "test_resource.cbd (deposed #0): Destroying...")
+
+	got, err := tf.StateAttr("test_resource.cbd", "optional_force_new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "2" {
+		t.Errorf("test_resource.cbd has optional_force_new %q after replacement; want \"2\"", got)
+	}
+}
+
+func TestReplaceTainted(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// REPLACE
+	if err := tf.Replace("test_resource.foo"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	if rs := state.RootModule().Resources["test_resource.foo"]; rs == nil || !rs.Primary.Tainted {
+		t.Fatalf("test_resource.foo is not tainted after Replace")
+	}
+
+	_, stderr, err = tf.Run("plan", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	plan, err := tf.Plan("tfplan")
+	if err != nil {
+		t.Fatalf("failed to read plan file: %s", err)
+	}
+	assertPlanTally(t, plan, 1, 0, 1)
+	if got, want := replacementMode(plan, "test_resource.foo"), "DeleteThenCreate"; got != want {
+		t.Errorf("wrong replacement mode %q; want %q", got, want)
+	}
+
+	stdout, stderr, err := tf.Run("apply", "tfplan")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Resources: 1 added, 0 changed, 1 destroyed") {
+		t.Errorf("incorrect apply tally; want 1 added and 1 destroyed:\n%s", stdout)
+	}
+	state, err = tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	if rs := state.RootModule().Resources["test_resource.foo"]; rs == nil || rs.Primary.Tainted {
+		t.Errorf("test_resource.foo is missing or still tainted after apply")
+	}
+
+	//// REPLACE NONEXISTENT
+	err = tf.Replace("test_resource.nonexistent")
+	if err == nil {
+		t.Fatalf("Replace succeeded for a resource that does not exist")
+	}
+	if !strings.Contains(err.Error(), "test_resource.nonexistent couldn't be found") {
+		t.Errorf("wrong error for nonexistent resource: %s", err)
+	}
+}
@@ -0,0 +1,85 @@
+package e2etest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIsTransientFailure(t *testing.T) {
+	tests := map[string]bool{
+		"": false,
+		"Error loading state: HTTP remote state internal server error":                                             true,
+		"Get https://releases.hashicorp.com/: read tcp 10.0.0.1:1234->1.2.3.4:443: read: connection reset by peer": true,
+		"dial tcp 127.0.0.1:1: connect: connection refused":                                                        true,
+		"net/http: TLS handshake timeout":                                                                          true,
+		"Failed to upload state: HTTP error: 503":                                                                  true,
+		"Unexpected HTTP response code 502":                                                                        true,
+		"Error loading files Error parsing main.tf: At 5:1: expected: IDENT | STRING got: RBRACE":                  false,
+		"HTTP remote state endpoint requires auth":                                                                 false,
+		"Unexpected HTTP response code 403":                                                                        false,
+	}
+
+	for stderr, want := range tests {
+		t.Run(stderr, func(t *testing.T) {
+			if got := isTransientFailure(stderr); got != want {
+				t.Errorf("wrong result %t; want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestRunWithRetry(t *testing.T) {
+	t.Parallel()
+
+	// The http backend stands in for a flaky remote service here: the
+	// server fails the first request with an internal server error and
+	// then behaves as if there is no state yet.
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		if n == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	t.Run("transient", func(t *testing.T) {
+		tf := newTerraform("http-backend")
+		defer tf.Close()
+
+		_, stderr, err := tf.RunWithRetry(3, 10*time.Millisecond, "init", "-backend-config=address="+server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error after retry: %s\nstderr:\n%s", err, stderr)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if requests < 2 {
+			t.Errorf("server saw %d requests; want the failed one to be retried", requests)
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		tf := newTerraform("validate-error")
+		defer tf.Close()
+
+		start := time.Now()
+		_, stderr, err := tf.RunWithRetry(3, time.Minute, "init")
+		if err == nil {
+			t.Fatalf("init succeeded with an invalid configuration")
+		}
+		if elapsed := time.Since(start); elapsed > 30*time.Second {
+			t.Errorf("configuration error was retried (took %s)\nstderr:\n%s", elapsed, stderr)
+		}
+	})
+}
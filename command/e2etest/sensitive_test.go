@@ -0,0 +1,182 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSensitiveRedaction(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// The variable value flows both into a resource attribute that the
+	// provider marks as sensitive and into an output marked as sensitive,
+	// and must not appear in the output of any command.
+
+	tf := newTerraformWithMirror("sensitive", testPluginsDir)
+	defer tf.Close()
+
+	const secret = "hunter2"
+	const newSecret = "correct-horse"
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// PLAN
+	stdout, stderr, err := tf.RunPlain("plan", "-var", "password="+secret)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, secret)
+	if !strings.Contains(stdout, `optional_sensitive:`) || !strings.Contains(stdout, `"<sensitive>"`) {
+		t.Errorf("sensitive attribute is not shown as redacted in the plan:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "Plan: 1 to add, 0 to change, 0 to destroy") {
+		t.Errorf("incorrect plan tally; want 1 to add:\n%s", stdout)
+	}
+
+	//// APPLY
+	stdout, stderr, err = tf.RunPlain("apply", "-var", "password="+secret)
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, secret)
+	if !strings.Contains(stdout, "password = <sensitive>") {
+		t.Errorf("sensitive output is not shown as redacted after apply:\n%s", stdout)
+	}
+
+	//// PLAN CHANGE
+	// When the value changes, neither the old nor the new value may be
+	// shown in the per-attribute diff.
+	stdout, stderr, err = tf.RunPlain("plan", "-var", "password="+newSecret)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, secret)
+	assertRedacted(t, stdout, newSecret)
+	if !strings.Contains(stdout, `"<sensitive>" => "<sensitive>" (attribute changed)`) {
+		t.Errorf("changed sensitive attribute is not shown as redacted in the plan:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "Plan: 0 to add, 1 to change, 0 to destroy") {
+		t.Errorf("incorrect plan tally; want 1 to change:\n%s", stdout)
+	}
+
+	//// APPLY CHANGE
+	stdout, stderr, err = tf.RunPlain("apply", "-var", "password="+newSecret)
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, secret)
+	assertRedacted(t, stdout, newSecret)
+	if !strings.Contains(stdout, `optional_sensitive: "<sensitive>" => "<sensitive>"`) {
+		t.Errorf("changed sensitive attribute is not shown as redacted during apply:\n%s", stdout)
+	}
+}
+
+func TestRedactPatterns(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// Neither the resource attribute nor the output is marked as sensitive,
+	// so the value is masked only because it matches a pattern in the CLI
+	// configuration file.
+
+	tf := newTerraformWithMirror("redact", testPluginsDir)
+	defer tf.Close()
+
+	const token = "tok-0123abcd"
+	env := []string{"TERRAFORM_CONFIG=" + tf.Path("terraformrc")}
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// PLAN WITHOUT PATTERNS
+	stdout, stderr, err := tf.RunPlain("plan", "-var", "token="+token)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, token) {
+		t.Fatalf("value is missing from the plan without redaction, so redaction can't be tested:\n%s", stdout)
+	}
+
+	//// PLAN
+	stdout, stderr, err = tf.RunWithEnv(env, "plan", "-no-color", "-var", "token="+token)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, token)
+	if !strings.Contains(stdout, `optional:`) || !strings.Contains(stdout, `"(redacted)"`) {
+		t.Errorf("attribute is not shown as redacted in the plan:\n%s", stdout)
+	}
+
+	//// APPLY
+	stdout, stderr, err = tf.RunWithEnv(env, "apply", "-no-color", "-var", "token="+token)
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, token)
+	if !strings.Contains(stdout, "token = (redacted)") {
+		t.Errorf("output is not shown as redacted after apply:\n%s", stdout)
+	}
+
+	// Redaction applies only to output, so the real value must still be
+	// recorded in state.
+	got, err := tf.StateAttr("test_resource.foo", "optional")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != token {
+		t.Errorf("wrong value in state %q; want %q", got, token)
+	}
+
+	//// OUTPUT
+	stdout, stderr, err = tf.RunWithEnv(env, "output", "token")
+	if err != nil {
+		t.Fatalf("unexpected output error: %s\nstderr:\n%s", err, stderr)
+	}
+	assertRedacted(t, stdout, token)
+	stdout, stderr, err = tf.RunWithEnv(env, "output", "-json", "token")
+	if err != nil {
+		t.Fatalf("unexpected output error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, token) {
+		t.Errorf("value is missing from the JSON output:\n%s", stdout)
+	}
+
+	//// STATE PULL AND PUSH
+	// State that is pulled with redaction configured must be exactly what
+	// is stored, so that it can be pushed back without losing the value.
+	pulled, stderr, err := tf.RunWithEnv(env, "state", "pull")
+	if err != nil {
+		t.Fatalf("unexpected state pull error: %s\nstderr:\n%s", err, stderr)
+	}
+	stored, err := tf.ReadFile("terraform.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(pulled) != strings.TrimSpace(string(stored)) {
+		t.Fatalf("pulled state does not match the state file\npulled:\n%s\nstored:\n%s", pulled, stored)
+	}
+	if err := tf.WriteFile("pulled.tfstate", []byte(pulled), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = tf.RunWithEnv(env, "state", "push", "pulled.tfstate")
+	if err != nil {
+		t.Fatalf("unexpected state push error: %s\nstderr:\n%s", err, stderr)
+	}
+	repulled, stderr, err := tf.RunWithEnv(env, "state", "pull")
+	if err != nil {
+		t.Fatalf("unexpected state pull error: %s\nstderr:\n%s", err, stderr)
+	}
+	if repulled != pulled {
+		t.Errorf("state changed when pushed back\nbefore:\n%s\nafter:\n%s", pulled, repulled)
+	}
+}
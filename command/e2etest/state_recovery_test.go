@@ -0,0 +1,79 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStateCorruptionRecovery(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	// Refreshing writes the state again, so that the backup then matches
+	// the state produced by the apply.
+	_, stderr, err = tf.Run("refresh")
+	if err != nil {
+		t.Fatalf("unexpected refresh error: %s\nstderr:\n%s", err, stderr)
+	}
+	if !tf.FileExists("terraform.tfstate.backup") {
+		t.Fatalf("no state backup file was written")
+	}
+
+	//// CORRUPT
+	// Simulate a state file that was truncated part way through writing.
+	src, err := tf.ReadFile("terraform.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.WriteFile("terraform.tfstate", src[:len(src)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Terraform must refuse to proceed, rather than treating the state as
+	// empty and planning to create everything again.
+	stdout, stderr, exitCode, err := tf.RunExit("plan", "-no-color")
+	if err != nil {
+		t.Fatalf("failed to run plan: %s", err)
+	}
+	if exitCode != 1 {
+		t.Errorf("wrong exit code %d with a corrupt state file; want 1\nstdout:\n%s", exitCode, stdout)
+	}
+	if !strings.Contains(stderr, "Error loading state") {
+		t.Errorf("error does not explain that the state could not be read:\n%s", stderr)
+	}
+	if strings.Contains(stdout, "to add") {
+		t.Errorf("plan was produced despite the corrupt state file:\n%s", stdout)
+	}
+
+	//// RECOVER
+	// The documented recovery is to replace the state file with its backup.
+	backup, err := tf.ReadFile("terraform.tfstate.backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.WriteFile("terraform.tfstate", backup, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, exitCode, err = tf.RunExit("plan", "-detailed-exitcode")
+	if err != nil {
+		t.Fatalf("failed to run plan: %s", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("wrong exit code %d after restoring the backup; want 0 for no changes\nstderr:\n%s", exitCode, stderr)
+	}
+}
@@ -0,0 +1,652 @@
+package e2etest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+func TestStateAttr(t *testing.T) {
+	t.Parallel()
+
+	tf := newTerraform("empty")
+	defer tf.Close()
+
+	state := tfcore.NewState()
+	state.RootModule().Resources = map[string]*tfcore.ResourceState{
+		"null_resource.single": {
+			Type: "null_resource",
+			Primary: &tfcore.InstanceState{
+				ID: "1",
+				Attributes: map[string]string{
+					"id":    "1",
+					"empty": "",
+				},
+			},
+		},
+		"null_resource.counted.1": {
+			Type: "null_resource",
+			Primary: &tfcore.InstanceState{
+				ID:         "2",
+				Attributes: map[string]string{"id": "2"},
+			},
+		},
+	}
+	state.AddModule([]string{"root", "child"}).Resources = map[string]*tfcore.ResourceState{
+		"null_resource.single": {
+			Type: "null_resource",
+			Primary: &tfcore.InstanceState{
+				ID:         "3",
+				Attributes: map[string]string{"id": "3"},
+			},
+		},
+	}
+	if err := tf.SetLocalState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Addr, Attr string
+		Want       string
+		NotFound   bool
+	}{
+		{"null_resource.single", "id", "1", false},
+		{"null_resource.single", "empty", "", false},
+		{"null_resource.single", "missing", "", true},
+		{"null_resource.counted[1]", "id", "2", false},
+		{"null_resource.counted[0]", "id", "", true},
+		{"module.child.null_resource.single", "id", "3", false},
+		{"null_resource.missing", "id", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Addr+"."+test.Attr, func(t *testing.T) {
+			got, err := tf.StateAttr(test.Addr, test.Attr)
+			if test.NotFound {
+				if _, ok := err.(*stateNotFoundError); !ok {
+					t.Fatalf("wrong error %#v; want *stateNotFoundError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != test.Want {
+				t.Errorf("wrong value %q; want %q", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestBackendState(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("local-backend-path", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	state, err := tf.BackendState()
+	if err != nil {
+		t.Fatalf("failed to read state before apply: %s", err)
+	}
+	if state.HasResources() {
+		t.Errorf("state has resources before apply:\n%s", state)
+	}
+
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	state, err = tf.BackendState()
+	if err != nil {
+		t.Fatalf("failed to read state after apply: %s", err)
+	}
+	if state.RootModule().Resources["test_resource.test"] == nil {
+		t.Errorf("test_resource.test is missing from state:\n%s", state)
+	}
+
+	if !tf.FileExists("custom", "state.tfstate") {
+		t.Errorf("state was not written to the configured path")
+	}
+	if tf.FileExists("terraform.tfstate") {
+		t.Errorf("state was written to the default path")
+	}
+}
+
+func TestStateMvRm(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("state-manipulation", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	got, err := tf.StateList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"test_resource.a", "test_resource.b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong resources after apply\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if err := tf.StateMv("test_resource.a", "test_resource.moved"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = tf.StateList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"test_resource.b", "test_resource.moved"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong resources after move\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if err := tf.StateRm("test_resource.moved"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = tf.StateList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"test_resource.b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong resources after remove\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	// Terraform no longer knows about the object that was at
+	// test_resource.a, so it must plan to create a new one.
+	_, stderr, err = tf.Run("plan", "-out=tfplan")
+	if err != nil {
+		t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+	}
+	plan, err := tf.Plan("tfplan")
+	if err != nil {
+		t.Fatalf("failed to read plan file: %s", err)
+	}
+	assertPlanTally(t, plan, 1, 0, 0)
+	if got, want := planTargets(plan), []string{"test_resource.a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong resources in plan\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestLocalBackupState(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("count", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	_, stderr, err = tf.Run("apply", "-var", "instances=3")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	first, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+
+	_, stderr, err = tf.Run("apply", "-var", "instances=2")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	second, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	backup, err := tf.LocalBackupState()
+	if err != nil {
+		t.Fatalf("failed to read backup state file: %s", err)
+	}
+
+	if !backup.Equal(first) {
+		t.Errorf("backup does not match the state before the second apply\nbackup:\n%s\nfirst:\n%s", backup, first)
+	}
+	if backup.Equal(second) {
+		t.Errorf("backup matches the state after the second apply")
+	}
+	if got := len(second.RootModule().Resources); got != 2 {
+		t.Errorf("wrong number of resources in state %d; want 2", got)
+	}
+}
+
+func TestLocalBackupStateCompressed(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("count", testPluginsDir)
+	defer tf.Close()
+	env := []string{state.StateBackupCompressEnvVar + "=1"}
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	_, stderr, err = tf.RunWithEnv(env, "apply", "-var", "instances=3")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	first, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+
+	_, stderr, err = tf.RunWithEnv(env, "apply", "-var", "instances=2")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	if tf.FileExists("terraform.tfstate.backup") {
+		t.Errorf("uncompressed backup was written")
+	}
+	raw, err := tf.ReadFile("terraform.tfstate.backup.gz")
+	if err != nil {
+		t.Fatalf("failed to read compressed backup: %s", err)
+	}
+	if !bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		t.Fatalf("backup is not compressed:\n%q", raw)
+	}
+
+	backup, err := tf.LocalBackupState()
+	if err != nil {
+		t.Fatalf("failed to read backup state file: %s", err)
+	}
+	if !backup.Equal(first) {
+		t.Errorf("backup does not match the state before the second apply\nbackup:\n%s\nfirst:\n%s", backup, first)
+	}
+
+	// The compressed backup is used just like an uncompressed one.
+	stdout, stderr, err := tf.Run("state", "list", "-state", "terraform.tfstate.backup.gz")
+	if err != nil {
+		t.Fatalf("unexpected state list error: %s\nstderr:\n%s", err, stderr)
+	}
+	if got := strings.Count(stdout, "test_resource.x["); got != 3 {
+		t.Errorf("wrong number of resources in backup %d; want 3\n%s", got, stdout)
+	}
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+
+	//// COMPRESSION TURNED OFF
+	// The compressed backup is now older than the uncompressed one, and so
+	// it is removed rather than left to be restored by mistake.
+	second, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	_, stderr, err = tf.Run("apply", "-var", "instances=1")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if tf.FileExists("terraform.tfstate.backup.gz") {
+		t.Errorf("stale compressed backup was left behind")
+	}
+	backup, err = tf.LocalBackupState()
+	if err != nil {
+		t.Fatalf("failed to read backup state file: %s", err)
+	}
+	if !backup.Equal(second) {
+		t.Errorf("backup does not match the state before the third apply\nbackup:\n%s\nsecond:\n%s", backup, second)
+	}
+
+	//// BOTH BACKUPS
+	if err := tf.WriteFile("terraform.tfstate.backup.gz", raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tf.LocalBackupState(); err == nil {
+		t.Errorf("no error reading the backup when both formats exist")
+	}
+}
+
+func TestStateSerial(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// Remote backends rely on the serial to detect when a state has been
+	// written by someone else, so it must increase whenever the state
+	// changes but stay the same when it doesn't.
+
+	tf := newTerraformWithMirror("count", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	apply := func(instances string) int64 {
+		_, stderr, err := tf.Run("apply", "-var", "instances="+instances)
+		if err != nil {
+			t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+		}
+		serial, err := tf.StateSerial()
+		if err != nil {
+			t.Fatalf("failed to read state serial: %s", err)
+		}
+		return serial
+	}
+
+	first := apply("1")
+	second := apply("2")
+	if second <= first {
+		t.Errorf("serial did not increase after a change: %d, then %d", first, second)
+	}
+	if unchanged := apply("2"); unchanged != second {
+		t.Errorf("serial changed after an apply with no changes: %d, then %d", second, unchanged)
+	}
+}
+
+func TestStateWriteStable(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// Terraform reuses the buffers that it encodes states into, so this
+	// checks that what is written to disk only ever reflects the state
+	// being written, however many times a state has been written before.
+
+	tf := newTerraformWithMirror("count", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	apply := func(instances string) []byte {
+		_, stderr, err := tf.Run("apply", "-var", "instances="+instances)
+		if err != nil {
+			t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+		}
+		src, err := tf.ReadFile("terraform.tfstate")
+		if err != nil {
+			t.Fatalf("failed to read state file: %s", err)
+		}
+
+		// The file must be exactly what a fresh encoding of the same
+		// state produces, with nothing left over from an earlier write.
+		state, err := tfcore.ReadState(bytes.NewReader(src))
+		if err != nil {
+			t.Fatalf("failed to read state file: %s", err)
+		}
+		var want bytes.Buffer
+		if err := tfcore.WriteState(state, &want); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(src, want.Bytes()) {
+			t.Fatalf("state file after apply with %s instances is not in normal form\ngot:\n%s\nwant:\n%s", instances, src, want.Bytes())
+		}
+		return src
+	}
+
+	large := apply("3")
+	if again := apply("3"); !bytes.Equal(again, large) {
+		t.Errorf("state file changed after an apply with no changes\nbefore:\n%s\nafter:\n%s", large, again)
+	}
+
+	small := apply("1")
+	if len(small) >= len(large) {
+		t.Fatalf("state file did not shrink after removing instances")
+	}
+	for _, removed := range []string{"test_resource.x.1", "test_resource.x.2", "instance 2"} {
+		if bytes.Contains(small, []byte(removed)) {
+			t.Errorf("state file still contains %q after removing instances:\n%s", removed, small)
+		}
+	}
+	if again := apply("1"); !bytes.Equal(again, small) {
+		t.Errorf("state file changed after an apply with no changes\nbefore:\n%s\nafter:\n%s", small, again)
+	}
+}
+
+func TestStateLineage(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+	//
+	// The lineage identifies a state for as long as it exists, so that a
+	// state from somewhere else can't be mistaken for a newer version of it.
+
+	tf := newTerraformWithMirror("count", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply", "-var", "instances=1")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	lineage, err := tf.StateLineage()
+	if err != nil {
+		t.Fatalf("failed to read state lineage: %s", err)
+	}
+	if lineage == "" {
+		t.Fatalf("state has no lineage after the first apply")
+	}
+
+	checkLineage := func(after string) {
+		got, err := tf.StateLineage()
+		if err != nil {
+			t.Fatalf("failed to read state lineage after %s: %s", after, err)
+		}
+		if got != lineage {
+			t.Errorf("lineage changed after %s: %q; want %q", after, got, lineage)
+		}
+	}
+
+	//// PLAN AND APPLY
+	for _, instances := range []string{"3", "2"} {
+		_, stderr, err = tf.Run("plan", "-var", "instances="+instances)
+		if err != nil {
+			t.Fatalf("unexpected plan error: %s\nstderr:\n%s", err, stderr)
+		}
+		checkLineage("plan")
+		_, stderr, err = tf.Run("apply", "-var", "instances="+instances)
+		if err != nil {
+			t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+		}
+		checkLineage("apply")
+	}
+
+	//// PUSH UNRELATED STATE
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+	unrelated := state.DeepCopy()
+	unrelated.Lineage = "00000000-0000-0000-0000-000000000000"
+	unrelated.Serial++
+	var buf bytes.Buffer
+	if err := tfcore.WriteState(unrelated, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.WriteFile("unrelated.tfstate", buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = tf.Run("state", "push", "unrelated.tfstate")
+	if err == nil {
+		t.Fatalf("state push succeeded with a different lineage")
+	}
+	if !strings.Contains(stderr, "The lineages do not match!") {
+		t.Errorf("wrong error for state with a different lineage:\n%s", stderr)
+	}
+	checkLineage("rejected state push")
+
+	//// DESTROY
+	_, stderr, err = tf.Run("destroy", "-force", "-var", "instances=2")
+	if err != nil {
+		t.Fatalf("unexpected destroy error: %s\nstderr:\n%s", err, stderr)
+	}
+	checkLineage("destroy")
+}
+
+func TestStatePushPull(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("test-provider", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+	_, stderr, err = tf.Run("apply")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	original, err := tf.BackendState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//// ROUND TRIP
+	// Pushing back exactly what was pulled must not change anything.
+	if err := tf.StatePush(original); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tf.BackendState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(original) || got.Serial != original.Serial || got.Lineage != original.Lineage {
+		t.Errorf("state changed by pushing it back unmodified\ngot:  %s\nwant: %s", got, original)
+	}
+
+	//// MODIFIED
+	modified := original.DeepCopy()
+	modified.RootModule().Resources["test_resource.foo"].Primary.Attributes["optional"] = "surgery"
+	if err := tf.StatePush(modified); err != nil {
+		t.Fatal(err)
+	}
+	got, err = tf.BackendState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := got.RootModule().Resources["test_resource.foo"].Primary.Attributes["optional"]; v != "surgery" {
+		t.Errorf("modified attribute was not persisted: %q", v)
+	}
+	if got.Lineage != original.Lineage {
+		t.Errorf("lineage changed from %q to %q", original.Lineage, got.Lineage)
+	}
+	if got.Serial <= original.Serial {
+		t.Errorf("serial did not increase after pushing a modified state: %d, then %d", original.Serial, got.Serial)
+	}
+
+	//// OLDER SERIAL
+	// The original state is now older than the one in the backend.
+	err = tf.StatePush(original)
+	if err == nil {
+		t.Fatalf("state push succeeded with an older serial")
+	}
+	if !strings.Contains(err.Error(), "The destination state has a higher serial number!") {
+		t.Errorf("wrong error for state with an older serial: %s", err)
+	}
+	if err := tf.StatePush(original, "-force"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = tf.BackendState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.RootModule().Resources["test_resource.foo"].Primary.Attributes["optional"]; ok {
+		t.Errorf("forced push of the original state did not replace the modified state")
+	}
+}
+
+// syntheticState returns a state with the given number of test_resource
+// instances in the root module, each with a handful of attributes, for
+// measuring how Terraform copes with large states.
+func syntheticState(n int) *tfcore.State {
+	state := tfcore.NewState()
+	mod := state.RootModule()
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("id-%d", i)
+		mod.Resources[fmt.Sprintf("test_resource.foo.%d", i)] = &tfcore.ResourceState{
+			Type:     "test_resource",
+			Provider: "test",
+			Primary: &tfcore.InstanceState{
+				ID: id,
+				Attributes: map[string]string{
+					"id":                     id,
+					"required":               fmt.Sprintf("instance %d", i),
+					"computed_from_required": fmt.Sprintf("instance %d", i),
+					"computed_read_only":     "value_from_api",
+					"required_map.%":         "1",
+					"required_map.key":       "value",
+					"computed_list.#":        "2",
+					"computed_list.0":        "listval1",
+					"computed_list.1":        "listval2",
+				},
+			},
+		}
+	}
+	return state
+}
+
+func BenchmarkLocalStateRead(b *testing.B) {
+	const resources = 10000
+
+	tf := newTerraform("empty")
+	defer tf.Close()
+
+	var buf bytes.Buffer
+	if err := tfcore.WriteState(syntheticState(resources), &buf); err != nil {
+		b.Fatal(err)
+	}
+	if err := tf.WriteFile("terraform.tfstate", buf.Bytes(), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		state, err := tf.LocalState()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if got := len(state.RootModule().Resources); got != resources {
+			b.Fatalf("wrong number of resources %d; want %d", got, resources)
+		}
+	}
+}
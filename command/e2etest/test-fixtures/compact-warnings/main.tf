@@ -0,0 +1,17 @@
+resource "test_resource" "foo" {
+  required            = "yes"
+  optional_deprecated = "still used"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "bar" {
+  required            = "yes"
+  optional_deprecated = "also still used"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,12 @@
+variable "instances" {
+  default = 3
+}
+
+resource "test_resource" "x" {
+  count    = "${var.instances}"
+  required = "instance ${count.index}"
+
+  required_map = {
+    key = "value"
+  }
+}
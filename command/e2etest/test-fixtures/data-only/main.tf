@@ -0,0 +1,11 @@
+variable "name" {
+  default = "world"
+}
+
+data "test_data_source" "greeting" {
+  input = "Hello, ${var.name}!"
+}
+
+output "greeting" {
+  value = "${data.test_data_source.greeting.output}"
+}
@@ -0,0 +1,12 @@
+resource "test_resource_timestamp" "a" {
+  name    = "a"
+  log_dir = "${path.cwd}"
+  delay   = "500ms"
+}
+
+resource "test_resource_timestamp" "b" {
+  name    = "b"
+  log_dir = "${path.cwd}"
+
+  depends_on = ["test_resource_timestamp.a"]
+}
@@ -0,0 +1,8 @@
+resource "test_resource" "foo" {
+  required            = "yes"
+  optional_deprecated = "still used"
+
+  required_map = {
+    key = "value"
+  }
+}
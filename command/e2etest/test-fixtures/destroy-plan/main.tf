@@ -0,0 +1,15 @@
+resource "test_resource" "a" {
+  required = "a"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "b" {
+  required = "${test_resource.a.id}"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,24 @@
+# Lots of map and set elements, so that any dependence on map iteration
+# order is likely to show up as a difference between two plans.
+variable "keys" {
+  default = ["a", "b", "c", "d", "e", "f", "g", "h"]
+}
+
+variable "instances" {
+  default = 8
+}
+
+resource "test_resource" "foo" {
+  count    = "${var.instances}"
+  required = "${element(var.keys, count.index)}"
+  set      = ["${var.keys}"]
+
+  required_map = {
+    a = "1"
+    b = "2"
+    c = "3"
+    d = "4"
+    e = "5"
+    f = "6"
+  }
+}
@@ -0,0 +1,11 @@
+terraform {
+  backend "http" {}
+}
+
+resource "test_resource" "foo" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,8 @@
+resource "test_resource" "baz" {
+  count    = 100
+  required = "baz ${count.index}"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,22 @@
+resource "test_resource" "foo" {
+  count    = 500
+  required = "foo ${count.index}"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "bar" {
+  count              = 100
+  required           = "bar ${count.index}"
+  optional_sensitive = "hidden"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+module "child" {
+  source = "./child"
+}
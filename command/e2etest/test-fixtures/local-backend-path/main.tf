@@ -0,0 +1,13 @@
+terraform {
+  backend "local" {
+    path = "custom/state.tfstate"
+  }
+}
+
+resource "test_resource" "test" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,9 @@
+module "child" {
+  source = "./modules/child"
+
+  greeting = "hello"
+}
+
+output "child_greeting" {
+  value = "${module.child.greeting}"
+}
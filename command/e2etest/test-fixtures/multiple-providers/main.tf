@@ -0,0 +1,22 @@
+provider "null" {
+  version = "~> 1.0"
+}
+
+provider "random" {
+  version = "~> 1.0"
+}
+
+provider "template" {
+  version = "~> 1.0"
+}
+
+resource "null_resource" "test" {
+}
+
+resource "random_id" "test" {
+  byte_length = 4
+}
+
+data "template_file" "test" {
+  template = "Hello World"
+}
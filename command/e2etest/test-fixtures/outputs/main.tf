@@ -0,0 +1,26 @@
+resource "test_resource" "foo" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+output "computed" {
+  value = "${test_resource.foo.computed_read_only}"
+}
+
+output "secret" {
+  value     = "hunter2"
+  sensitive = true
+}
+
+output "list" {
+  value = ["a", "b"]
+}
+
+output "map" {
+  value = {
+    key = "value"
+  }
+}
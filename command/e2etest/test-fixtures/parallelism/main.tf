@@ -0,0 +1,13 @@
+variable "instances" {
+  default = 4
+}
+
+variable "delay" {
+  default = "500ms"
+}
+
+resource "test_resource_concurrency" "test" {
+  count   = "${var.instances}"
+  log_dir = "${path.cwd}"
+  delay   = "${var.delay}"
+}
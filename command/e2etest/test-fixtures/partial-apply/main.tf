@@ -0,0 +1,14 @@
+resource "test_resource_fail" "before" {
+}
+
+resource "test_resource_fail" "failing" {
+  fail          = true
+  depends_on_id = "${test_resource_fail.before.id}"
+}
+
+resource "test_resource_fail" "dependent" {
+  depends_on_id = "${test_resource_fail.failing.id}"
+}
+
+resource "test_resource_fail" "unrelated" {
+}
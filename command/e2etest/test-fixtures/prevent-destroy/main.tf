@@ -0,0 +1,11 @@
+resource "test_resource" "protected" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
@@ -0,0 +1,11 @@
+# The test replaces MODULE_URL with the address of a stub module server that
+# requires credentials, which responds with the location of modules/child.
+module "child" {
+  source = "MODULE_URL"
+
+  greeting = "hello"
+}
+
+output "child_greeting" {
+  value = "${module.child.greeting}"
+}
@@ -0,0 +1,14 @@
+variable "greeting" {
+}
+
+resource "test_resource" "test" {
+  required = "${var.greeting}"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+output "greeting" {
+  value = "${test_resource.test.required}"
+}
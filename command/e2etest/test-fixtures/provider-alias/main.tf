@@ -0,0 +1,32 @@
+provider "test" {
+  label = "default"
+}
+
+provider "test" {
+  alias = "west"
+  label = "west"
+}
+
+data "test_provider_label" "default" {
+}
+
+data "test_provider_label" "west" {
+  provider = "test.west"
+}
+
+output "default_label" {
+  value = "${data.test_provider_label.default.label}"
+}
+
+output "west_label" {
+  value = "${data.test_provider_label.west.label}"
+}
+
+resource "test_resource" "west" {
+  provider = "test.west"
+  required = "${data.test_provider_label.west.label}"
+
+  required_map = {
+    key = "value"
+  }
+}
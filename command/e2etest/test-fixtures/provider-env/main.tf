@@ -0,0 +1,15 @@
+# The provider's label is not set here, so it is taken from the
+# TEST_PROVIDER_LABEL environment variable.
+provider "test" {
+}
+
+data "test_provider_label" "test" {
+}
+
+resource "test_resource" "foo" {
+  required = "${data.test_provider_label.test.label}"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,11 @@
+provider "test" {
+  version = "~> 1.0"
+}
+
+resource "test_resource" "foo" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+}
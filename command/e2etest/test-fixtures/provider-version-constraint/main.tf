@@ -0,0 +1,6 @@
+provider "null" {
+  version = ">= 0.1.0, < 2.0.0"
+}
+
+resource "null_resource" "test" {
+}
@@ -0,0 +1,15 @@
+variable "token" {
+}
+
+resource "test_resource" "foo" {
+  required = "yes"
+  optional = "${var.token}"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+output "token" {
+  value = "${var.token}"
+}
@@ -0,0 +1,11 @@
+variable "value" {}
+
+resource "test_resource" "qux" {
+  count    = 40
+  required = "qux ${count.index}"
+  optional = "${var.value}"
+
+  required_map = {
+    key = "value"
+  }
+}
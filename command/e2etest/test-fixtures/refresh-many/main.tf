@@ -0,0 +1,33 @@
+# Enough resources that refresh reads many of them at the same time, with
+# lists, sets, maps and dependencies that all have to be written out in the
+# same order however the reads happen to interleave.
+variable "keys" {
+  default = ["a", "b", "c", "d", "e", "f", "g", "h"]
+}
+
+resource "test_resource" "foo" {
+  count    = 60
+  required = "foo ${count.index}"
+  set      = ["${var.keys}"]
+  list     = ["${var.keys}"]
+
+  required_map = {
+    a = "1"
+    b = "2"
+    c = "3"
+  }
+}
+
+resource "test_resource" "bar" {
+  count    = 60
+  required = "${element(test_resource.foo.*.computed_from_required, count.index)}"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+module "child" {
+  source = "./child"
+  value  = "${join(",", test_resource.bar.*.required)}"
+}
@@ -0,0 +1,19 @@
+variable "b_value" {
+  default = "one"
+}
+
+resource "test_resource_reads" "a" {
+  name    = "a"
+  log_dir = "${path.cwd}"
+}
+
+resource "test_resource_reads" "b" {
+  name    = "b"
+  log_dir = "${path.cwd}"
+  value   = "${test_resource_reads.a.id}-${var.b_value}"
+}
+
+resource "test_resource_reads" "c" {
+  name    = "c"
+  log_dir = "${path.cwd}"
+}
@@ -0,0 +1,25 @@
+variable "generation" {
+  default = "1"
+}
+
+resource "test_resource" "default" {
+  required           = "yes"
+  optional_force_new = "${var.generation}"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "cbd" {
+  required           = "yes"
+  optional_force_new = "${var.generation}"
+
+  required_map = {
+    key = "value"
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
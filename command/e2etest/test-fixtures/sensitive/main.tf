@@ -0,0 +1,16 @@
+variable "password" {
+}
+
+resource "test_resource" "foo" {
+  required           = "yes"
+  optional_sensitive = "${var.password}"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+output "password" {
+  value     = "${var.password}"
+  sensitive = true
+}
@@ -0,0 +1,46 @@
+# Each of these providers is a copy of the "test" provider under another
+# name, installed by the test.
+
+provider "alpha" {}
+
+provider "beta" {}
+
+provider "gamma" {}
+
+provider "delta" {}
+
+resource "test_resource" "alpha" {
+  provider = "alpha"
+  required = "alpha"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "beta" {
+  provider = "beta"
+  required = "beta"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "gamma" {
+  provider = "gamma"
+  required = "gamma"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "delta" {
+  provider = "delta"
+  required = "delta"
+
+  required_map = {
+    key = "value"
+  }
+}
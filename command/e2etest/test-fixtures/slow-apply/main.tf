@@ -0,0 +1,11 @@
+resource "test_resource" "test" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+
+  provisioner "local-exec" {
+    command = "sleep 120"
+  }
+}
@@ -0,0 +1,4 @@
+resource "test_resource_concurrency" "slow" {
+  log_dir = "${path.cwd}"
+  delay   = "5s"
+}
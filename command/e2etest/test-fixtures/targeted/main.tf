@@ -0,0 +1,23 @@
+resource "test_resource" "dep" {
+  required = "dep"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "a" {
+  required = "${test_resource.dep.id}"
+
+  required_map = {
+    key = "value"
+  }
+}
+
+resource "test_resource" "other" {
+  required = "other"
+
+  required_map = {
+    key = "value"
+  }
+}
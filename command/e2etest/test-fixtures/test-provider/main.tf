@@ -0,0 +1,7 @@
+resource "test_resource" "foo" {
+  required = "yes"
+
+  required_map = {
+    key = "value"
+  }
+}
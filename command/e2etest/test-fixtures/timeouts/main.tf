@@ -0,0 +1,13 @@
+variable "create_timeout" {
+  default = "50ms"
+}
+
+resource "test_resource_timestamp" "slow" {
+  name    = "slow"
+  log_dir = "${path.cwd}"
+  delay   = "500ms"
+
+  timeouts {
+    create = "${var.create_timeout}"
+  }
+}
@@ -0,0 +1,14 @@
+variable "names" {
+  type = "list"
+}
+
+variable "tags" {
+  type = "map"
+}
+
+resource "test_resource" "foo" {
+  count    = "${length(var.names)}"
+  required = "${var.names[count.index]}"
+
+  required_map = "${var.tags}"
+}
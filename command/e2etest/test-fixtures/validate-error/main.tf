@@ -0,0 +1,7 @@
+resource "test_resource" "foo" {
+  required = "${var.missing}"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,5 @@
+variable "foo" {}
+
+output "foo" {
+  value = "${var.foo}"
+}
@@ -0,0 +1,7 @@
+resource "test_resource" "test" {
+  required = "${terraform.workspace}"
+
+  required_map = {
+    key = "value"
+  }
+}
@@ -0,0 +1,50 @@
+package e2etest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTimeouts(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access. The fixture's resource takes 500ms to create,
+	// and its create timeout comes from a variable.
+
+	tf := newTerraformWithMirror("timeouts", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	//// TOO SHORT
+	_, stderr, err = tf.RunPlain("apply")
+	if err == nil {
+		t.Fatalf("apply succeeded with a create timeout shorter than the create")
+	}
+	// The errors are introduced by an "Error applying plan:" heading.
+	_, errors := splitDiagnostics(stderr)
+	if len(errors) != 2 {
+		t.Fatalf("wrong errors %#v\nstderr:\n%s", errors, stderr)
+	}
+	timeoutErr := errors[1]
+	if want := "test_resource_timestamp.slow: timeout while waiting"; !strings.HasPrefix(timeoutErr, want) {
+		t.Errorf("wrong error %q; want prefix %q", timeoutErr, want)
+	}
+	if !strings.Contains(timeoutErr, "timeout: 50ms") {
+		t.Errorf("error does not report the configured timeout: %s", timeoutErr)
+	}
+	assertNotInState(t, tf, "test_resource_timestamp.slow")
+
+	//// WIDENED
+	_, stderr, err = tf.Run("apply", "-var", "create_timeout=1m")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+	if _, err := tf.StateAttr("test_resource_timestamp.slow", "id"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,173 @@
+package e2etest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ValidateOutput is the result of running "terraform validate".
+type ValidateOutput struct {
+	// Valid is true if the validate command exited successfully. It may
+	// still have reported warnings.
+	Valid bool
+
+	Diagnostics []ValidateDiagnostic
+}
+
+// ValidateDiagnostic is a single error or warning reported by
+// "terraform validate".
+type ValidateDiagnostic struct {
+	// Severity is either "error" or "warning".
+	Severity string
+
+	// Summary is the message as printed, without any leading bullet.
+	Summary string
+}
+
+// Validate runs "terraform validate" with the given additional arguments
+// and returns the errors and warnings it reported.
+//
+// This version of Terraform produces only human-oriented output from
+// validate, so the diagnostics are recovered by parsing that output. Source
+// locations are not reported separately, but are often included in the
+// summary of errors from the configuration parser.
+//
+// A configuration that fails validation does not cause an error to be
+// returned; instead, the result has Valid set to false. The error return is
+// reserved for situations where Terraform could not be run at all or where
+// it exited in an unexpected way.
+func (t *terraform) Validate(args ...string) (*ValidateOutput, error) {
+	args = append([]string{"validate", "-no-color"}, args...)
+	stdout, stderr, exitCode, err := t.RunExit(args...)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 && exitCode != 1 {
+		return nil, fmt.Errorf("validate exited with status %d\nstderr:\n%s", exitCode, stderr)
+	}
+
+	return &ValidateOutput{
+		Valid:       exitCode == 0,
+		Diagnostics: parseValidateOutput(stdout + stderr),
+	}, nil
+}
+
+// parseValidateOutput extracts the errors and warnings from the output of
+// "terraform validate", in the order they were printed.
+func parseValidateOutput(output string) []ValidateDiagnostic {
+	var diags []ValidateDiagnostic
+	severity := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "Warnings:":
+			severity = "warning"
+		case line == "Errors:":
+			severity = "error"
+		case strings.HasPrefix(line, "Error validating: "):
+			// The remainder is a count of errors, which are then listed
+			// as bullets on the following lines.
+			severity = "error"
+		case strings.HasPrefix(line, "Error loading files "):
+			diags = append(diags, ValidateDiagnostic{
+				Severity: "error",
+				Summary:  strings.TrimPrefix(line, "Error loading files "),
+			})
+		case strings.HasPrefix(line, "* ") && severity != "":
+			diags = append(diags, ValidateDiagnostic{
+				Severity: severity,
+				Summary:  strings.TrimPrefix(line, "* "),
+			})
+		}
+	}
+	return diags
+}
+
+func TestParseValidateOutput(t *testing.T) {
+	tests := map[string]struct {
+		Output string
+		Want   []ValidateDiagnostic
+	}{
+		"valid": {
+			"",
+			nil,
+		},
+		"load error": {
+			"Error loading files Error parsing main.tf: At 5:1: expected: IDENT | STRING got: RBRACE\n\n",
+			[]ValidateDiagnostic{
+				{"error", "Error parsing main.tf: At 5:1: expected: IDENT | STRING got: RBRACE"},
+			},
+		},
+		"config errors": {
+			"Error validating: 2 error(s) occurred:\n\n* first\n* second\n\n",
+			[]ValidateDiagnostic{
+				{"error", "first"},
+				{"error", "second"},
+			},
+		},
+		"context warnings and errors": {
+			"There are warnings and/or errors related to your configuration. Please\n" +
+				"fix these before continuing.\n\n" +
+				"Warnings:\n\n  * careful\n\n" +
+				"Errors:\n\n  * broken\n",
+			[]ValidateDiagnostic{
+				{"warning", "careful"},
+				{"error", "broken"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := parseValidateOutput(test.Output)
+			if !reflect.DeepEqual(got, test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	// These tests use -check-variables=false so that the configuration is
+	// checked without needing any provider plugins.
+
+	t.Run("valid", func(t *testing.T) {
+		tf := newTerraform("test-provider")
+		defer tf.Close()
+
+		got, err := tf.Validate("-check-variables=false")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Valid || len(got.Diagnostics) != 0 {
+			t.Errorf("wrong result for a valid configuration\n%#v", got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tf := newTerraform("validate-error")
+		defer tf.Close()
+
+		got, err := tf.Validate("-check-variables=false")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Valid {
+			t.Errorf("configuration is valid; want invalid")
+		}
+		if len(got.Diagnostics) != 1 {
+			t.Fatalf("wrong number of diagnostics %d; want 1\n%#v", len(got.Diagnostics), got.Diagnostics)
+		}
+		diag := got.Diagnostics[0]
+		if diag.Severity != "error" {
+			t.Errorf("wrong severity %q; want error", diag.Severity)
+		}
+		if !strings.Contains(diag.Summary, "unknown variable referenced: 'missing'") {
+			t.Errorf("wrong summary %q", diag.Summary)
+		}
+	})
+}
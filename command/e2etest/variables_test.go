@@ -0,0 +1,93 @@
+package e2etest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestVariablesFromEnvironment(t *testing.T) {
+	t.Parallel()
+
+	// This test uses only the local backend and an output, so it does not
+	// need any providers and can run without network access.
+
+	tf := newTerraform("var-from-env")
+	defer tf.Close()
+
+	// The second definition of TF_VAR_foo should win over the first.
+	env := []string{
+		"TF_VAR_foo=ignored",
+		"TF_VAR_foo=bar",
+	}
+	_, stderr, err := tf.RunWithEnv(env, "apply", "-input=false")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	state, err := tf.LocalState()
+	if err != nil {
+		t.Fatalf("failed to read state file: %s", err)
+	}
+
+	output := state.RootModule().Outputs["foo"]
+	if output == nil {
+		t.Fatalf("output \"foo\" is missing from state")
+	}
+	if got, want := output.Value, "bar"; got != want {
+		t.Errorf("wrong value for output \"foo\"\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestVariablesTyped(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("typed-vars", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	vars := map[string]interface{}{
+		"names": []interface{}{"a", "b", "c"},
+		"tags": map[string]interface{}{
+			"env": "test",
+		},
+	}
+	_, stderr, err = tf.RunWithVars(vars, "apply", "-input=false")
+	if err != nil {
+		t.Fatalf("unexpected apply error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	got, err := tf.StateList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"test_resource.foo[0]",
+		"test_resource.foo[1]",
+		"test_resource.foo[2]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong resources in state\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	for i, name := range []string{"a", "b", "c"} {
+		addr := fmt.Sprintf("test_resource.foo[%d]", i)
+		if got, err := tf.StateAttr(addr, "required"); err != nil {
+			t.Error(err)
+		} else if got != name {
+			t.Errorf("wrong value for %s.required %q; want %q", addr, got, name)
+		}
+	}
+	if got, err := tf.StateAttr("test_resource.foo[0]", "required_map.env"); err != nil {
+		t.Error(err)
+	} else if got != "test" {
+		t.Errorf("wrong value for required_map.env %q; want %q", got, "test")
+	}
+}
@@ -0,0 +1,105 @@
+package e2etest
+
+import (
+	"reflect"
+	"testing"
+
+	tfcore "github.com/hashicorp/terraform/terraform"
+)
+
+func TestWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	// This test uses the "test" provider from our own build, so it can run
+	// without network access.
+
+	tf := newTerraformWithMirror("workspaces", testPluginsDir)
+	defer tf.Close()
+
+	_, stderr, err := tf.Run("init")
+	if err != nil {
+		t.Fatalf("unexpected init error: %s\nstderr:\n%s", err, stderr)
+	}
+
+	for _, name := range []string{"staging", "prod"} {
+		if err := tf.WorkspaceNew(name); err != nil {
+			t.Fatal(err)
+		}
+		_, stderr, err = tf.Run("apply")
+		if err != nil {
+			t.Fatalf("unexpected apply error in workspace %q: %s\nstderr:\n%s", name, err, stderr)
+		}
+	}
+
+	got, err := tf.WorkspaceList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"default", "prod", "staging"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong workspaces\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	// The fixture's resource records which workspace it was created in, so
+	// we can verify that each workspace has its own separate state.
+	for _, name := range []string{"staging", "prod"} {
+		f, err := tf.OpenFile("terraform.tfstate.d", name, "terraform.tfstate")
+		if err != nil {
+			t.Fatalf("failed to open state for workspace %q: %s", name, err)
+		}
+		state, err := tfcore.ReadState(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("failed to read state for workspace %q: %s", name, err)
+		}
+
+		resources := state.RootModule().Resources
+		if len(resources) != 1 {
+			t.Errorf("wrong number of resources in workspace %q: %d; want 1", name, len(resources))
+			continue
+		}
+		rs := resources["test_resource.test"]
+		if rs == nil {
+			t.Errorf("test_resource.test is missing from workspace %q", name)
+			continue
+		}
+		if got := rs.Primary.Attributes["required"]; got != name {
+			t.Errorf("wrong resource in workspace %q: it belongs to %q", name, got)
+		}
+	}
+
+	// Nothing was applied in the default workspace.
+	if tf.FileExists("terraform.tfstate") {
+		state, err := tf.LocalState()
+		if err != nil {
+			t.Fatalf("failed to read default workspace state: %s", err)
+		}
+		if state.HasResources() {
+			t.Errorf("default workspace has resources:\n%s", state)
+		}
+	}
+
+	scanStateFilesForSecrets(tf, t, defaultSecretPatterns())
+
+	// A workspace must have an empty state before it can be deleted, so
+	// we'll destroy everything in the current workspace (prod) first.
+	_, stderr, err = tf.Run("destroy", "-force")
+	if err != nil {
+		t.Fatalf("unexpected destroy error: %s\nstderr:\n%s", err, stderr)
+	}
+	if err := tf.WorkspaceSelect("default"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.WorkspaceDelete("prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = tf.WorkspaceList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"default", "staging"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong workspaces after delete\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -41,3 +42,38 @@ func (v *FlagStringSlice) Set(raw string) error {
 
 	return nil
 }
+
+// FlagRefresh is a flag.Value implementation for the -refresh option of the
+// plan command. As well as the usual boolean values, it accepts "targeted"
+// to refresh only the resources whose configuration has changed.
+type FlagRefresh struct {
+	Refresh  bool
+	Targeted bool
+}
+
+func (v *FlagRefresh) String() string {
+	if v.Targeted {
+		return "targeted"
+	}
+	return strconv.FormatBool(v.Refresh)
+}
+
+func (v *FlagRefresh) Set(raw string) error {
+	if raw == "targeted" {
+		v.Refresh, v.Targeted = true, true
+		return nil
+	}
+
+	refresh, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fmt.Errorf("must be true, false or targeted")
+	}
+	v.Refresh, v.Targeted = refresh, false
+	return nil
+}
+
+// IsBoolFlag allows the flag to be given as just -refresh, as it could be
+// when it was a boolean flag.
+func (v *FlagRefresh) IsBoolFlag() bool {
+	return true
+}
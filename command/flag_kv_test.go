@@ -66,3 +66,33 @@ func TestFlagStringKV(t *testing.T) {
 		}
 	}
 }
+
+func TestFlagRefresh_impl(t *testing.T) {
+	var _ flag.Value = new(FlagRefresh)
+}
+
+func TestFlagRefresh(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output FlagRefresh
+		Error  bool
+	}{
+		{"true", FlagRefresh{Refresh: true}, false},
+		{"false", FlagRefresh{}, false},
+		{"0", FlagRefresh{}, false},
+		{"targeted", FlagRefresh{Refresh: true, Targeted: true}, false},
+		{"sometimes", FlagRefresh{Refresh: true}, true},
+	}
+
+	for _, tc := range cases {
+		f := FlagRefresh{Refresh: true}
+		err := f.Set(tc.Input)
+		if err != nil != tc.Error {
+			t.Fatalf("bad error. Input: %#v\n\nError: %s", tc.Input, err)
+		}
+
+		if !reflect.DeepEqual(f, tc.Output) {
+			t.Fatalf("bad: %#v", f)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package format
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/terraform"
@@ -24,13 +26,39 @@ type PlanOpts struct {
 	ModuleDepth int
 }
 
+// planNothing is the formatted version of a plan with no changes.
+const planNothing = "This plan does nothing."
+
 // Plan takes a plan and returns a
 func Plan(opts *PlanOpts) string {
 	p := opts.Plan
 	if p.Diff == nil || p.Diff.Empty() {
-		return "This plan does nothing."
+		return planNothing
 	}
 
+	buf := new(bytes.Buffer)
+	formatPlan(buf, opts)
+
+	return strings.TrimSpace(buf.String())
+}
+
+// PlanStream writes the same text that Plan returns to w. Each resource is
+// written as soon as it has been formatted, rather than building the whole
+// result in memory first, which matters for plans with many changes.
+func PlanStream(w io.Writer, opts *PlanOpts) error {
+	p := opts.Plan
+	if p.Diff == nil || p.Diff.Empty() {
+		_, err := io.WriteString(w, planNothing)
+		return err
+	}
+
+	tw := &trimSpaceWriter{w: w}
+	formatPlan(tw, opts)
+
+	return tw.err
+}
+
+func formatPlan(w io.Writer, opts *PlanOpts) {
 	if opts.Color == nil {
 		opts.Color = &colorstring.Colorize{
 			Colors: colorstring.DefaultColors,
@@ -38,22 +66,70 @@ func Plan(opts *PlanOpts) string {
 		}
 	}
 
-	buf := new(bytes.Buffer)
-	for _, m := range p.Diff.Modules {
+	for _, m := range opts.Plan.Diff.Modules {
 		if len(m.Path)-1 <= opts.ModuleDepth || opts.ModuleDepth == -1 {
-			formatPlanModuleExpand(buf, m, opts)
+			formatPlanModuleExpand(w, m, opts)
 		} else {
-			formatPlanModuleSingle(buf, m, opts)
+			formatPlanModuleSingle(w, m, opts)
+		}
+	}
+}
+
+// trimSpaceWriter writes to w what strings.TrimSpace would return for
+// everything written to it. Leading space is dropped, and space is held back
+// until something other than space is written after it, so that any space
+// at the very end is never written.
+//
+// The first error from w is kept in err, and nothing more is written after
+// it.
+type trimSpaceWriter struct {
+	w       io.Writer
+	started bool
+	pending string
+	err     error
+}
+
+func (t *trimSpaceWriter) Write(p []byte) (int, error) {
+	return t.WriteString(string(p))
+}
+
+// WriteString is like Write, but saves converting the formatted plan text
+// to a byte slice and back for each write.
+func (t *trimSpaceWriter) WriteString(s string) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	n := len(s)
+
+	if !t.started {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return n, nil
 		}
+		t.started = true
 	}
 
-	return strings.TrimSpace(buf.String())
+	end := len(strings.TrimRightFunc(s, unicode.IsSpace))
+	if end > 0 {
+		if t.pending != "" {
+			if _, t.err = io.WriteString(t.w, t.pending); t.err != nil {
+				return 0, t.err
+			}
+			t.pending = ""
+		}
+		if _, t.err = io.WriteString(t.w, s[:end]); t.err != nil {
+			return 0, t.err
+		}
+	}
+	t.pending += s[end:]
+
+	return n, nil
 }
 
 // formatPlanModuleExpand will output the given module and all of its
 // resources.
 func formatPlanModuleExpand(
-	buf *bytes.Buffer, m *terraform.ModuleDiff, opts *PlanOpts) {
+	buf io.Writer, m *terraform.ModuleDiff, opts *PlanOpts) {
 	// Ignore empty diffs
 	if m.Empty() {
 		return
@@ -137,7 +213,7 @@ func formatPlanModuleExpand(
 			extraStr = extraStr + opts.Color.Color(" [red][bold](new resource required)")
 		}
 
-		buf.WriteString(opts.Color.Color(fmt.Sprintf(
+		io.WriteString(buf, opts.Color.Color(fmt.Sprintf(
 			"[%s]%s %s%s\n",
 			color, symbol, addrStr, extraStr)))
 
@@ -185,7 +261,7 @@ func formatPlanModuleExpand(
 				} else {
 					u = attrDiff.Old
 				}
-				buf.WriteString(fmt.Sprintf(
+				io.WriteString(buf, fmt.Sprintf(
 					"      %s:%s %#v => %#v%s\n",
 					attrK,
 					strings.Repeat(" ", keyLen-len(attrK)),
@@ -193,7 +269,7 @@ func formatPlanModuleExpand(
 					v,
 					updateMsg))
 			} else {
-				buf.WriteString(fmt.Sprintf(
+				io.WriteString(buf, fmt.Sprintf(
 					"      %s:%s %#v%s\n",
 					attrK,
 					strings.Repeat(" ", keyLen-len(attrK)),
@@ -203,14 +279,14 @@ func formatPlanModuleExpand(
 		}
 
 		// Write the reset color so we don't overload the user's terminal
-		buf.WriteString(opts.Color.Color("[reset]\n"))
+		io.WriteString(buf, opts.Color.Color("[reset]\n"))
 	}
 }
 
 // formatPlanModuleSingle will output the given module and all of its
 // resources.
 func formatPlanModuleSingle(
-	buf *bytes.Buffer, m *terraform.ModuleDiff, opts *PlanOpts) {
+	buf io.Writer, m *terraform.ModuleDiff, opts *PlanOpts) {
 	// Ignore empty diffs
 	if m.Empty() {
 		return
@@ -232,11 +308,11 @@ func formatPlanModuleSingle(
 		symbol = "-"
 	}
 
-	buf.WriteString(opts.Color.Color(fmt.Sprintf(
+	io.WriteString(buf, opts.Color.Color(fmt.Sprintf(
 		"[%s]%s %s\n",
 		color, symbol, moduleName)))
-	buf.WriteString(fmt.Sprintf(
+	io.WriteString(buf, fmt.Sprintf(
 		"    %d resource(s)",
 		len(m.Resources)))
-	buf.WriteString(opts.Color.Color("[reset]\n"))
+	io.WriteString(buf, opts.Color.Color("[reset]\n"))
 }
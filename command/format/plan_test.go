@@ -1,6 +1,10 @@
 package format
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 
@@ -168,3 +172,167 @@ func TestPlan_nestedDataSource(t *testing.T) {
 		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
 	}
 }
+
+// Test that streaming a plan gives exactly the same output as formatting it
+func TestPlanStream(t *testing.T) {
+	for _, depth := range []int{-1, 0} {
+		for _, disable := range []bool{true, false} {
+			opts := &PlanOpts{
+				Plan: testPlanLarge(3),
+				Color: &colorstring.Colorize{
+					Colors:  colorstring.DefaultColors,
+					Disable: disable,
+				},
+				ModuleDepth: depth,
+			}
+
+			expected := Plan(opts)
+
+			var buf bytes.Buffer
+			if err := PlanStream(&buf, opts); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if actual := buf.String(); actual != expected {
+				t.Fatalf("expected:\n\n%q\n\ngot:\n\n%q", expected, actual)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := PlanStream(&buf, &PlanOpts{Plan: &terraform.Plan{}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := buf.String(); actual != planNothing {
+		t.Fatalf("expected %q, got %q", planNothing, actual)
+	}
+}
+
+func TestTrimSpaceWriter(t *testing.T) {
+	cases := [][]string{
+		{},
+		{" \n", "\t"},
+		{"  a", "\n", "b  ", " \n"},
+		{"\n\na b\n", "c\n\n"},
+	}
+
+	for _, writes := range cases {
+		var buf bytes.Buffer
+		w := &trimSpaceWriter{w: &buf}
+		for _, s := range writes {
+			if n, err := io.WriteString(w, s); err != nil || n != len(s) {
+				t.Fatalf("bad write of %q: %d, %s", s, n, err)
+			}
+		}
+
+		expected := strings.TrimSpace(strings.Join(writes, ""))
+		if actual := buf.String(); actual != expected {
+			t.Fatalf("%q: expected %q, got %q", writes, expected, actual)
+		}
+	}
+}
+
+// Test that streaming a large plan never holds more than one resource's worth
+// of output at a time
+func TestPlanStream_bounded(t *testing.T) {
+	opts := &PlanOpts{
+		Plan:        testPlanLarge(1000),
+		ModuleDepth: -1,
+	}
+
+	w := &maxWriteSizeWriter{}
+	if err := PlanStream(w, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if w.total < 100000 {
+		t.Fatalf("only %d bytes written", w.total)
+	}
+	if w.max > 200 {
+		t.Fatalf("wrote %d bytes at once", w.max)
+	}
+}
+
+// maxWriteSizeWriter discards what is written to it, recording the total
+// number of bytes and the size of the largest single write.
+type maxWriteSizeWriter struct {
+	total, max int
+}
+
+func (w *maxWriteSizeWriter) Write(p []byte) (int, error) {
+	w.total += len(p)
+	if len(p) > w.max {
+		w.max = len(p)
+	}
+	return len(p), nil
+}
+
+func BenchmarkPlan(b *testing.B) {
+	opts := &PlanOpts{
+		Plan:        testPlanLarge(10000),
+		ModuleDepth: -1,
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		io.WriteString(ioutil.Discard, Plan(opts))
+	}
+}
+
+func BenchmarkPlanStream(b *testing.B) {
+	opts := &PlanOpts{
+		Plan:        testPlanLarge(10000),
+		ModuleDepth: -1,
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := PlanStream(ioutil.Discard, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// testPlanLarge returns a plan that creates, updates, replaces and destroys
+// n resources each, spread across the root module and a child.
+func testPlanLarge(n int) *terraform.Plan {
+	root := &terraform.ModuleDiff{
+		Path:      []string{"root"},
+		Resources: map[string]*terraform.InstanceDiff{},
+	}
+	child := &terraform.ModuleDiff{
+		Path:      []string{"root", "child"},
+		Resources: map[string]*terraform.InstanceDiff{},
+	}
+
+	for i := 0; i < n; i++ {
+		root.Resources[fmt.Sprintf("aws_instance.create.%d", i)] = &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami":      &terraform.ResourceAttrDiff{New: fmt.Sprintf("ami-%d", i), RequiresNew: true},
+				"id":       &terraform.ResourceAttrDiff{NewComputed: true},
+				"password": &terraform.ResourceAttrDiff{New: "secret", Sensitive: true},
+			},
+		}
+		root.Resources[fmt.Sprintf("aws_instance.update.%d", i)] = &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"tags.Name": &terraform.ResourceAttrDiff{Old: "old", New: "new"},
+			},
+		}
+		child.Resources[fmt.Sprintf("aws_instance.replace.%d", i)] = &terraform.InstanceDiff{
+			Destroy: true,
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{Old: "ami-old", New: "ami-new", RequiresNew: true},
+			},
+		}
+		child.Resources[fmt.Sprintf("aws_instance.destroy.%d", i)] = &terraform.InstanceDiff{
+			Destroy: true,
+		}
+	}
+
+	return &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{root, child},
+		},
+	}
+}
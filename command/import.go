@@ -46,6 +46,11 @@ func (c *ImportCommand) Run(args []string) int {
 		return 1
 	}
 
+	if c.Meta.parallelism, err = c.Meta.Parallelism(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	args = cmdFlags.Args()
 	if len(args) != 2 {
 		c.Ui.Error("The import command expects two arguments.")
@@ -5,20 +5,34 @@ import (
 	"log"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-getter"
+	"github.com/mattn/go-isatty"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/helper/variables"
+	"github.com/hashicorp/terraform/helper/wrappedstreams"
 	"github.com/hashicorp/terraform/plugin"
 	"github.com/hashicorp/terraform/plugin/discovery"
 	"github.com/hashicorp/terraform/terraform"
 )
 
+const (
+	// PluginInstallParallelismDefault is the number of providers that init
+	// downloads at once unless overridden by PluginInstallParallelismEnvVar.
+	PluginInstallParallelismDefault = 4
+
+	// PluginInstallParallelismEnvVar is the name of the environment variable
+	// that can be used to limit the number of providers downloaded at once.
+	PluginInstallParallelismEnvVar = "TF_PLUGIN_INSTALL_PARALLELISM"
+)
+
 // InitCommand is a Command implementation that takes a Terraform
 // module and clones it to the working directory.
 type InitCommand struct {
@@ -32,6 +46,10 @@ type InitCommand struct {
 	// by default, but it can be overridden here as a way to mock fetching
 	// providers for tests.
 	providerInstaller discovery.Installer
+
+	// progressLock serializes the reports from downloadProgress, which
+	// may be called concurrently for providers installed at once.
+	progressLock sync.Mutex
 }
 
 func (c *InitCommand) Run(args []string) int {
@@ -71,11 +89,15 @@ func (c *InitCommand) Run(args []string) int {
 
 	// set getProvider if we don't have a test version already
 	if c.providerInstaller == nil {
-		c.providerInstaller = &discovery.ProviderInstaller{
-			Dir: c.pluginDir(),
+		installer := &discovery.ProviderInstaller{
+			Dir:                   c.pluginDir(),
 			PluginProtocolVersion: plugin.Handshake.ProtocolVersion,
 			SkipVerify:            !flagVerifyPlugins,
 		}
+		if showDownloadProgress(c.color, wrappedstreams.Stderr()) {
+			installer.Progress = c.downloadProgress
+		}
+		c.providerInstaller = installer
 	}
 
 	// Validate the arg count
@@ -273,6 +295,47 @@ func (c *InitCommand) copyConfigFromModule(dst, src, pwd string) error {
 	return module.GetCopy(dst, src)
 }
 
+// pluginInstallParallelism returns the number of providers that init may
+// download at once, which can be overridden by
+// PluginInstallParallelismEnvVar.
+func pluginInstallParallelism() (int, error) {
+	v := os.Getenv(PluginInstallParallelismEnvVar)
+	if v == "" {
+		return PluginInstallParallelismDefault, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive whole number, not %q", PluginInstallParallelismEnvVar, v)
+	}
+	return n, nil
+}
+
+// showDownloadProgress returns true if init should report the progress of
+// provider downloads. The reports are only useful to someone watching
+// them, so they are left out when stderr is not a terminal, and also with
+// -no-color, which is how automation usually asks for plain output.
+func showDownloadProgress(color bool, stderr *os.File) bool {
+	return color && isatty.IsTerminal(stderr.Fd())
+}
+
+// downloadProgress is the discovery.ProviderInstaller Progress callback
+// for init, which reports how much of a provider has been downloaded.
+func (c *InitCommand) downloadProgress(provider string, v discovery.Version, downloaded, total int64) {
+	msg := fmt.Sprintf("- Downloading plugin for provider %q: %s", provider, formatByteCount(downloaded))
+	if total >= 0 {
+		msg += " of " + formatByteCount(total)
+	}
+
+	c.progressLock.Lock()
+	defer c.progressLock.Unlock()
+	c.Ui.Error(msg)
+}
+
+// formatByteCount formats a number of bytes for a progress report.
+func formatByteCount(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/1e6)
+}
+
 // Load the complete module tree, and fetch any missing providers.
 // This method outputs its own Ui.
 func (c *InitCommand) getProviders(path string, state *terraform.State, upgrade bool) error {
@@ -297,6 +360,18 @@ func (c *InitCommand) getProviders(path string, state *terraform.State, upgrade
 	}
 
 	requirements := terraform.ModuleTreeDependencies(mod, state).AllPluginRequirements()
+
+	// Providers that are already running don't need to be installed, and
+	// there is no executable of theirs to record in the lock file.
+	reattached, err := reattachedProviders()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error getting plugins: %s", err))
+		return err
+	}
+	for name := range reattached {
+		delete(requirements, name)
+	}
+
 	if len(requirements) == 0 {
 		// nothing to initialize
 		return nil
@@ -310,11 +385,29 @@ func (c *InitCommand) getProviders(path string, state *terraform.State, upgrade
 
 	var errs error
 	if c.getPlugins {
-		for provider, reqd := range missing {
+		parallelism, err := pluginInstallParallelism()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error getting plugins: %s", err))
+			return err
+		}
+
+		// The providers are installed concurrently, so we announce them all
+		// up front and then report any errors in a consistent order once
+		// they are all done.
+		providers := make([]string, 0, len(missing))
+		for provider := range missing {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
 			c.Ui.Output(fmt.Sprintf("- Downloading plugin for provider %q...", provider))
-			_, err := c.providerInstaller.Get(provider, reqd.Versions)
+		}
+
+		_, installErrs := discovery.GetAll(c.providerInstaller, missing, parallelism)
 
-			if err != nil {
+		for _, provider := range providers {
+			reqd := missing[provider]
+			if err := installErrs[provider]; err != nil {
 				switch err {
 				case discovery.ErrorNoSuchProvider:
 					c.Ui.Error(fmt.Sprintf(errProviderNotFound, provider, DefaultPluginVendorDir))
@@ -370,18 +463,29 @@ func (c *InitCommand) getProviders(path string, state *terraform.State, upgrade
 	// fail with an error instructing the user to re-run this command.
 	available = c.providerPluginSet() // re-discover to see newly-installed plugins
 	chosen := choosePlugins(available, requirements)
+	names := make([]string, 0, len(chosen))
+	for name := range chosen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 	digests := map[string][]byte{}
-	for name, meta := range chosen {
-		digest, err := meta.SHA256()
+	for _, name := range names {
+		// Every plugin is read even after one fails, so that all of the
+		// unreadable plugins are reported at once.
+		digest, err := chosen[name].SHA256()
 		if err != nil {
-			c.Ui.Error(fmt.Sprintf("failed to read provider plugin %s: %s", meta.Path, err))
-			return err
+			c.Ui.Error(fmt.Sprintf("failed to read plugin for provider %q: %s", name, err))
+			errs = multierror.Append(errs, err)
+			continue
 		}
 		digests[name] = digest
 		if c.ignorePluginChecksum {
 			digests[name] = nil
 		}
 	}
+	if errs != nil {
+		return errs
+	}
 	err = c.providerPluginsLock().Write(digests)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("failed to save provider manifest: %s", err))
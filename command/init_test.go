@@ -593,6 +593,85 @@ func TestInit_getProvider(t *testing.T) {
 	}
 }
 
+func TestInit_downloadProgress(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &InitCommand{
+		Meta: Meta{Ui: ui},
+	}
+
+	v := discovery.VersionStr("1.2.3").MustParse()
+	c.downloadProgress("aws", v, 1500000, 12000000)
+	c.downloadProgress("aws", v, 2500000, -1)
+
+	want := `- Downloading plugin for provider "aws": 1.5 MB of 12.0 MB
+- Downloading plugin for provider "aws": 2.5 MB
+`
+	if got := ui.ErrorWriter.String(); got != want {
+		t.Fatalf("wrong progress output\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	if got := ui.OutputWriter.String(); got != "" {
+		t.Fatalf("unexpected output on stdout:\n%s", got)
+	}
+}
+
+func TestInit_showDownloadProgress(t *testing.T) {
+	f, err := ioutil.TempFile("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// A file is never a terminal, whatever the color setting, so there is
+	// no progress when the output is captured, as in CI.
+	for _, color := range []bool{true, false} {
+		if showDownloadProgress(color, f) {
+			t.Errorf("progress shown for a file with color %t", color)
+		}
+	}
+}
+
+func TestInit_getProviderParallelismInvalid(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("init-get-providers"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	os.Setenv(PluginInstallParallelismEnvVar, "0")
+	defer os.Unsetenv(PluginInstallParallelismEnvVar)
+
+	ui := new(cli.MockUi)
+	m := Meta{
+		testingOverrides: metaOverridesForProvider(testProvider()),
+		Ui:               ui,
+	}
+
+	installer := &mockProviderInstaller{
+		Providers: map[string][]string{
+			"exact":        []string{"1.2.3"},
+			"greater_than": []string{"2.3.4"},
+			"between":      []string{"2.3.4"},
+		},
+
+		Dir: m.pluginDir(),
+	}
+
+	c := &InitCommand{
+		Meta:              m,
+		providerInstaller: installer,
+	}
+
+	args := []string{"-backend=false"}
+	if code := c.Run(args); code == 0 {
+		t.Fatalf("expected error, got output: \n%s", ui.OutputWriter.String())
+	}
+
+	if !strings.Contains(ui.ErrorWriter.String(), PluginInstallParallelismEnvVar) {
+		t.Fatalf("error should mention %s:\n%s", PluginInstallParallelismEnvVar, ui.ErrorWriter.String())
+	}
+}
+
 // make sure we can locate providers in various paths
 func TestInit_findVendoredProviders(t *testing.T) {
 	// Create a temporary working directory that is empty
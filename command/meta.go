@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -37,6 +38,7 @@ type Meta struct {
 	Color            bool             // True if output should be colored
 	GlobalPluginDirs []string         // Additional paths to search for plugins
 	PluginOverrides  *PluginOverrides // legacy overrides from .terraformrc file
+	OutputRedaction  *OutputRedaction // redact_patterns from .terraformrc file
 	Ui               cli.Ui           // Ui for output
 
 	// ExtraHooks are extra hooks to add to the context.
@@ -112,6 +114,9 @@ type Meta struct {
 	// init.
 	//
 	// reconfigure forces init to ignore any stored configuration.
+	//
+	// compactWarnings lists each distinct validation warning just once,
+	// without the usual explanation.
 	statePath        string
 	stateOutPath     string
 	backupPath       string
@@ -122,6 +127,7 @@ type Meta struct {
 	stateLockTimeout time.Duration
 	forceInitCopy    bool
 	reconfigure      bool
+	compactWarnings  bool
 
 	// errWriter is the write side of a pipe for the FlagSet output. We need to
 	// keep track of this to close previous pipes between tests. Normal
@@ -187,6 +193,36 @@ const (
 	InputModeEnvVar = "TF_INPUT"
 )
 
+// Parallelism returns the limit on parallel operations for this command,
+// which comes from -parallelism if it was given, then ParallelismEnvVar,
+// and otherwise is worked out from the number of CPUs.
+//
+// Only the commands that walk a graph and accept -parallelism call this,
+// once their flags are parsed, and they store the result back in
+// m.parallelism so that contextOpts passes it on to the context. Other
+// commands leave the limit to the context's own default, and so don't fail
+// on an invalid ParallelismEnvVar.
+func (m *Meta) Parallelism() (int, error) {
+	if m.parallelism != 0 {
+		log.Printf("[INFO] command: parallelism is %d (from -parallelism)", m.parallelism)
+		return m.parallelism, nil
+	}
+
+	if v := os.Getenv(ParallelismEnvVar); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%s must be a positive whole number, not %q", ParallelismEnvVar, v)
+		}
+		log.Printf("[INFO] command: parallelism is %d (from %s)", n, ParallelismEnvVar)
+		return n, nil
+	}
+
+	cpus := runtime.NumCPU()
+	n := defaultParallelism(cpus)
+	log.Printf("[INFO] command: parallelism is %d (default for %d CPUs)", n, cpus)
+	return n, nil
+}
+
 // InputMode returns the type of input we should ask for in the form of
 // terraform.InputMode which is passed directly to Context.Input.
 func (m *Meta) InputMode() terraform.InputMode {
@@ -431,6 +467,21 @@ func (m *Meta) process(args []string, vars bool) ([]string, error) {
 func (m *Meta) uiHook() *UiHook {
 	return &UiHook{
 		Colorize: m.Colorize(),
+		Ui:       m.redactUi(),
+	}
+}
+
+// redactUi returns the Ui to use for human-oriented rendering, such as
+// plans, state and apply progress, which masks any matches of the
+// redact_patterns from the CLI configuration. Machine-readable output,
+// such as that of "state pull" or "output -json", must use m.Ui instead so
+// that it is never altered.
+func (m *Meta) redactUi() cli.Ui {
+	if m.OutputRedaction == nil || len(m.OutputRedaction.Patterns) == 0 {
+		return m.Ui
+	}
+	return &RedactUi{
+		Patterns: m.OutputRedaction.Patterns,
 		Ui:       m.Ui,
 	}
 }
@@ -94,15 +94,18 @@ func (m *Meta) Backend(opts *BackendOpts) (backend.Enhanced, error) {
 		log.Printf("[INFO] command: backend initialized: %T", b)
 	}
 
+	contextOpts := m.contextOpts()
+
 	// Setup the CLI opts we pass into backends that support it
 	cliOpts := &backend.CLIOpts{
-		CLI:             m.Ui,
+		CLI:             m.redactUi(),
 		CLIColor:        m.Colorize(),
 		StatePath:       m.statePath,
 		StateOutPath:    m.stateOutPath,
 		StateBackupPath: m.backupPath,
-		ContextOpts:     m.contextOpts(),
+		ContextOpts:     contextOpts,
 		Input:           m.Input(),
+		CompactWarnings: m.compactWarnings,
 	}
 
 	// Don't validate if we have a plan.  Validation is normally harmless here,
@@ -168,7 +171,7 @@ func (m *Meta) Operation() *backend.Operation {
 		PlanOutBackend:   m.backendState,
 		Targets:          m.targets,
 		UIIn:             m.UIInput(),
-		UIOut:            m.Ui,
+		UIOut:            m.redactUi(),
 		Workspace:        m.Workspace(),
 		LockState:        m.stateLock,
 		StateLockTimeout: m.stateLockTimeout,
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/hashicorp/terraform/backend"
@@ -115,6 +116,55 @@ func TestMetaInputMode_envVar(t *testing.T) {
 	}
 }
 
+func TestMeta_parallelism(t *testing.T) {
+	old := os.Getenv(ParallelismEnvVar)
+	defer os.Setenv(ParallelismEnvVar, old)
+
+	cases := []struct {
+		Flag   int
+		EnvVar string
+		Want   int
+		Err    bool
+	}{
+		{0, "", defaultParallelism(runtime.NumCPU()), false},
+		{0, "3", 3, false},
+		{2, "3", 2, false},
+		{2, "", 2, false},
+		{0, "0", 0, true},
+		{0, "many", 0, true},
+		{2, "many", 2, false},
+	}
+
+	for _, tc := range cases {
+		os.Setenv(ParallelismEnvVar, tc.EnvVar)
+		m := &Meta{parallelism: tc.Flag}
+		got, err := m.Parallelism()
+		if (err != nil) != tc.Err {
+			t.Fatalf("-parallelism=%d %s=%q: err: %v", tc.Flag, ParallelismEnvVar, tc.EnvVar, err)
+		}
+		if got != tc.Want {
+			t.Fatalf("-parallelism=%d %s=%q: got %d; want %d", tc.Flag, ParallelismEnvVar, tc.EnvVar, got, tc.Want)
+		}
+	}
+}
+
+func TestDefaultParallelism(t *testing.T) {
+	cases := map[int]int{
+		1:  10,
+		2:  10,
+		3:  12,
+		4:  16,
+		8:  32,
+		64: 32,
+	}
+
+	for cpus, want := range cases {
+		if got := defaultParallelism(cpus); got != want {
+			t.Errorf("%d CPUs: got %d; want %d", cpus, got, want)
+		}
+	}
+}
+
 func TestMetaInputMode_disable(t *testing.T) {
 	test = false
 	defer func() { test = true }()
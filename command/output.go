@@ -106,7 +106,7 @@ func (c *OutputCommand) Run(args []string) int {
 			c.Ui.Output(string(jsonOutputs))
 			return 0
 		} else {
-			c.Ui.Output(outputsAsString(state, modPath, nil, false))
+			c.redactUi().Output(outputsAsString(state, modPath, nil, false))
 			return 0
 		}
 	}
@@ -131,13 +131,13 @@ func (c *OutputCommand) Run(args []string) int {
 	} else {
 		switch output := v.Value.(type) {
 		case string:
-			c.Ui.Output(output)
+			c.redactUi().Output(output)
 			return 0
 		case []interface{}:
-			c.Ui.Output(formatListOutput("", "", output))
+			c.redactUi().Output(formatListOutput("", "", output))
 			return 0
 		case map[string]interface{}:
-			c.Ui.Output(formatMapOutput("", "", output))
+			c.redactUi().Output(formatMapOutput("", "", output))
 			return 0
 		default:
 			c.Ui.Error(fmt.Sprintf("Unknown output type: %T", v.Type))
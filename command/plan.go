@@ -17,7 +17,8 @@ type PlanCommand struct {
 }
 
 func (c *PlanCommand) Run(args []string) int {
-	var destroy, refresh, detailed bool
+	var destroy, detailed bool
+	refresh := FlagRefresh{Refresh: true}
 	var outPath string
 	var moduleDepth int
 
@@ -28,20 +29,26 @@ func (c *PlanCommand) Run(args []string) int {
 
 	cmdFlags := c.Meta.flagSet("plan")
 	cmdFlags.BoolVar(&destroy, "destroy", false, "destroy")
-	cmdFlags.BoolVar(&refresh, "refresh", true, "refresh")
+	cmdFlags.Var(&refresh, "refresh", "refresh")
 	c.addModuleDepthFlag(cmdFlags, &moduleDepth)
 	cmdFlags.StringVar(&outPath, "out", "", "path")
 	cmdFlags.IntVar(
-		&c.Meta.parallelism, "parallelism", DefaultParallelism, "parallelism")
+		&c.Meta.parallelism, "parallelism", 0, "parallelism")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
 	cmdFlags.BoolVar(&detailed, "detailed-exitcode", false, "detailed-exitcode")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.BoolVar(&c.Meta.compactWarnings, "compact-warnings", false, "compact warnings")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
 	}
 
+	if c.Meta.parallelism, err = c.Meta.Parallelism(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	configPath, err := ModulePath(cmdFlags.Args())
 	if err != nil {
 		c.Ui.Error(err.Error())
@@ -62,7 +69,7 @@ func (c *PlanCommand) Run(args []string) int {
 	}
 	if plan != nil {
 		// Disable refreshing no matter what since we only want to show the plan
-		refresh = false
+		refresh = FlagRefresh{}
 
 		// Set the config path to empty for backend loading
 		configPath = ""
@@ -97,7 +104,8 @@ func (c *PlanCommand) Run(args []string) int {
 	opReq.Destroy = destroy
 	opReq.Module = mod
 	opReq.Plan = plan
-	opReq.PlanRefresh = refresh
+	opReq.PlanRefresh = refresh.Refresh
+	opReq.PlanRefreshTargeted = refresh.Targeted
 	opReq.PlanOutPath = outPath
 	opReq.Type = backend.OperationTypePlan
 
@@ -146,6 +154,10 @@ Usage: terraform plan [options] [DIR-OR-PLAN]
 
 Options:
 
+  -compact-warnings   If Terraform produces any warnings that are not
+                      accompanied by errors, list each distinct warning just
+                      once and without the usual explanation.
+
   -destroy            If set, a plan will be generated to destroy all resources
                       managed by the given configuration and state.
 
@@ -170,9 +182,15 @@ Options:
   -out=path           Write a plan file to the given path. This can be used as
                       input to the "apply" command.
 
-  -parallelism=n      Limit the number of concurrent operations. Defaults to 10.
+  -parallelism=n      Limit the number of concurrent operations. Defaults to
+                      TF_PARALLELISM if set, or otherwise four per CPU,
+                      between 10 and 32.
 
-  -refresh=true       Update state prior to checking for differences.
+  -refresh=true       Update state prior to checking for differences. If set
+                      to "targeted", only resources whose configuration has
+                      changed, and the resources they depend on, are
+                      updated, so changes made outside of Terraform to any
+                      other resources won't be detected.
 
   -state=statefile    Path to a Terraform state file to use to look
                       up Terraform-managed resources. By default it will
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -25,6 +26,15 @@ import (
 // each that satisfies the given constraints.
 type multiVersionProviderResolver struct {
 	Available discovery.PluginMetaSet
+
+	// Reattached are the addresses of providers that are already running,
+	// keyed by provider name. These are used regardless of the version and
+	// checksum requirements, and in preference to any plugins in Available.
+	Reattached map[string]net.Addr
+
+	// ReattachedErr is any error from finding the reattached providers,
+	// which is reported when resolving.
+	ReattachedErr error
 }
 
 func choosePlugins(avail discovery.PluginMetaSet, reqd discovery.PluginRequirements) map[string]discovery.PluginMeta {
@@ -45,8 +55,20 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 	factories := make(map[string]terraform.ResourceProviderFactory, len(reqd))
 	var errs []error
 
+	if r.ReattachedErr != nil {
+		return nil, []error{r.ReattachedErr}
+	}
+	for name, addr := range r.Reattached {
+		if _, required := reqd[name]; required {
+			factories[name] = reattachedProviderFactory(addr)
+		}
+	}
+
 	chosen := choosePlugins(r.Available, reqd)
 	for name, req := range reqd {
+		if _, reattached := factories[name]; reattached {
+			continue
+		}
 		if newest, available := chosen[name]; available {
 			digest, err := newest.SHA256()
 			if err != nil {
@@ -220,8 +242,11 @@ func (m *Meta) providerPluginManuallyInstalledSet() discovery.PluginMetaSet {
 }
 
 func (m *Meta) providerResolver() terraform.ResourceProviderResolver {
+	reattached, err := reattachedProviders()
 	return &multiVersionProviderResolver{
-		Available: m.providerPluginSet(),
+		Available:     m.providerPluginSet(),
+		Reattached:    reattached,
+		ReattachedErr: err,
 	}
 }
 
@@ -0,0 +1,128 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+
+	plugin "github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ReattachProvidersEnvVar is the name of the environment variable that
+// describes provider plugins that are already running, which Terraform
+// connects to instead of starting plugin processes of its own.
+//
+// This is primarily for tests and for debugging providers. The value is a
+// JSON object whose keys are provider names and whose values have the same
+// shape as the reattach configuration of the go-plugin library:
+//
+//	{"test": {"Protocol": "netrpc", "Pid": 1234, "Test": true,
+//	          "Addr": {"Network": "unix", "String": "/tmp/plugin123"}}}
+//
+// Terraform never stops a reattached provider, so the Pid and Test
+// properties are accepted but not used. Whoever started the provider is
+// responsible for stopping it.
+const ReattachProvidersEnvVar = "TF_REATTACH_PROVIDERS"
+
+// reattachConfig is the description of a single running provider in the
+// value of ReattachProvidersEnvVar.
+type reattachConfig struct {
+	Protocol string
+	Pid      int
+	Test     bool
+	Addr     struct {
+		Network string
+		String  string
+	}
+}
+
+// reattachedProviders returns the address of each of the running providers
+// described by ReattachProvidersEnvVar, keyed by provider name. The result
+// is empty if the variable isn't set.
+func reattachedProviders() (map[string]net.Addr, error) {
+	raw := os.Getenv(ReattachProvidersEnvVar)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var configs map[string]reattachConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %s", ReattachProvidersEnvVar, err)
+	}
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	addrs := make(map[string]net.Addr, len(configs))
+	for _, name := range names {
+		c := configs[name]
+
+		// Providers in this version of Terraform only speak net/rpc, which
+		// is what go-plugin assumes when no protocol is given.
+		switch c.Protocol {
+		case "", string(plugin.ProtocolNetRPC):
+		default:
+			return nil, fmt.Errorf(
+				"invalid value for %s: provider %q uses unsupported protocol %q",
+				ReattachProvidersEnvVar, name, c.Protocol)
+		}
+
+		switch c.Addr.Network {
+		case "unix":
+			addrs[name] = &net.UnixAddr{Net: "unix", Name: c.Addr.String}
+		case "tcp":
+			addr, err := net.ResolveTCPAddr("tcp", c.Addr.String)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"invalid value for %s: provider %q: %s",
+					ReattachProvidersEnvVar, name, err)
+			}
+			addrs[name] = addr
+		default:
+			return nil, fmt.Errorf(
+				"invalid value for %s: provider %q uses unsupported network %q",
+				ReattachProvidersEnvVar, name, c.Addr.Network)
+		}
+
+		log.Printf("[INFO] using reattached provider %q at %s", name, addrs[name])
+	}
+
+	return addrs, nil
+}
+
+// reattachedProviderFactory returns a factory for a provider that is
+// already running and serving the plugin protocol at the given address.
+//
+// Each instance that the factory creates has its own connection, which is
+// left open until Terraform exits. Unlike the clients for the plugins that
+// Terraform starts, there is no process to kill when Terraform is done with
+// the provider.
+func reattachedProviderFactory(addr net.Addr) terraform.ResourceProviderFactory {
+	return func() (terraform.ResourceProvider, error) {
+		conn, err := net.Dial(addr.Network(), addr.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to reattached provider: %s", err)
+		}
+
+		rpcClient, err := plugin.NewRPCClient(conn, tfplugin.PluginMap)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := rpcClient.Dispense(tfplugin.ProviderPluginName)
+		if err != nil {
+			rpcClient.Close()
+			return nil, err
+		}
+
+		return raw.(terraform.ResourceProvider), nil
+	}
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	plugin "github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestReattachedProviders(t *testing.T) {
+	old := os.Getenv(ReattachProvidersEnvVar)
+	defer os.Setenv(ReattachProvidersEnvVar, old)
+
+	cases := map[string]struct {
+		EnvVar string
+		Want   map[string]string
+		Err    bool
+	}{
+		"unset": {
+			"",
+			map[string]string{},
+			false,
+		},
+		"unix": {
+			`{"test": {"Protocol": "netrpc", "Pid": 1, "Test": true, "Addr": {"Network": "unix", "String": "/tmp/plugin123"}}}`,
+			map[string]string{"test": "unix /tmp/plugin123"},
+			false,
+		},
+		"tcp without protocol": {
+			`{"test": {"Addr": {"Network": "tcp", "String": "127.0.0.1:1234"}}}`,
+			map[string]string{"test": "tcp 127.0.0.1:1234"},
+			false,
+		},
+		"grpc": {
+			`{"test": {"Protocol": "grpc", "Addr": {"Network": "unix", "String": "/tmp/plugin123"}}}`,
+			nil,
+			true,
+		},
+		"bad network": {
+			`{"test": {"Addr": {"Network": "udp", "String": "127.0.0.1:1234"}}}`,
+			nil,
+			true,
+		},
+		"not json": {
+			`test=/tmp/plugin123`,
+			nil,
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			os.Setenv(ReattachProvidersEnvVar, tc.EnvVar)
+			addrs, err := reattachedProviders()
+			if (err != nil) != tc.Err {
+				t.Fatalf("%s=%q: err: %v", ReattachProvidersEnvVar, tc.EnvVar, err)
+			}
+			if tc.Err {
+				return
+			}
+
+			got := make(map[string]string, len(addrs))
+			for name, addr := range addrs {
+				got[name] = addr.Network() + " " + addr.String()
+			}
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("wrong addresses\ngot:  %#v\nwant: %#v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestMultiVersionProviderResolver_reattached(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	l, err := net.Listen("unix", filepath.Join(td, "provider.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p := testProvider()
+	server := &plugin.RPCServer{
+		Plugins: map[string]plugin.Plugin{
+			tfplugin.ProviderPluginName: &tfplugin.ResourceProviderPlugin{
+				F: func() terraform.ResourceProvider { return p },
+			},
+		},
+		Stdout: new(bytes.Buffer),
+		Stderr: new(bytes.Buffer),
+	}
+	go server.Serve(l)
+
+	// The requirements would not be met by any installed plugin, and
+	// the reattached provider must be used anyway.
+	reqd := discovery.PluginRequirements{
+		"test": &discovery.PluginConstraints{
+			Versions: discovery.ConstraintStr("1.0.0").MustParse(),
+			SHA256:   []byte("not the digest of anything"),
+		},
+	}
+	r := &multiVersionProviderResolver{
+		Available:  discovery.PluginMetaSet{},
+		Reattached: map[string]net.Addr{"test": l.Addr()},
+	}
+	factories, errs := r.ResolveProviders(reqd)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %s", errs)
+	}
+
+	provider, err := factories["test"]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%#v", p.ResourcesReturn)
+	if got := fmt.Sprintf("%#v", provider.Resources()); got != want {
+		t.Fatalf("wrong resources from reattached provider\ngot:  %s\nwant: %s", got, want)
+	}
+	if !p.ResourcesCalled {
+		t.Fatal("reattached provider was not called")
+	}
+}
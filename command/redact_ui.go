@@ -0,0 +1,57 @@
+package command
+
+import (
+	"regexp"
+
+	"github.com/mitchellh/cli"
+)
+
+// redactedValue replaces each match of a redaction pattern in output.
+const redactedValue = "(redacted)"
+
+// OutputRedaction holds the patterns for values that are masked in all
+// human-oriented output, regardless of whether they are marked as
+// sensitive. It is set from the redact_patterns setting in the .terraformrc
+// file.
+type OutputRedaction struct {
+	Patterns []*regexp.Regexp
+}
+
+// RedactUi is a Ui implementation that replaces every match of any of the
+// given patterns with "(redacted)" before passing messages on to the
+// wrapped Ui.
+type RedactUi struct {
+	Patterns []*regexp.Regexp
+	Ui       cli.Ui
+}
+
+func (u *RedactUi) Ask(query string) (string, error) {
+	return u.Ui.Ask(u.redact(query))
+}
+
+func (u *RedactUi) AskSecret(query string) (string, error) {
+	return u.Ui.AskSecret(u.redact(query))
+}
+
+func (u *RedactUi) Output(message string) {
+	u.Ui.Output(u.redact(message))
+}
+
+func (u *RedactUi) Info(message string) {
+	u.Ui.Info(u.redact(message))
+}
+
+func (u *RedactUi) Error(message string) {
+	u.Ui.Error(u.redact(message))
+}
+
+func (u *RedactUi) Warn(message string) {
+	u.Ui.Warn(u.redact(message))
+}
+
+func (u *RedactUi) redact(message string) string {
+	for _, pattern := range u.Patterns {
+		message = pattern.ReplaceAllLiteralString(message, redactedValue)
+	}
+	return message
+}
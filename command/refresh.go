@@ -29,11 +29,17 @@ func (c *RefreshCommand) Run(args []string) int {
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "", "path")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.BoolVar(&c.Meta.compactWarnings, "compact-warnings", false, "compact warnings")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
 	}
 
+	if c.Meta.parallelism, err = c.Meta.Parallelism(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	configPath, err := ModulePath(cmdFlags.Args())
 	if err != nil {
 		c.Ui.Error(err.Error())
@@ -88,7 +94,7 @@ func (c *RefreshCommand) Run(args []string) int {
 
 	// Output the outputs
 	if outputs := outputsAsString(op.State, terraform.RootModulePath, nil, true); outputs != "" {
-		c.Ui.Output(c.Colorize().Color(outputs))
+		c.redactUi().Output(c.Colorize().Color(outputs))
 	}
 
 	return 0
@@ -111,6 +117,10 @@ Options:
                       modifying. Defaults to the "-state-out" path with
                       ".backup" extension. Set to "-" to disable backup.
 
+  -compact-warnings   If Terraform produces any warnings that are not
+                      accompanied by errors, list each distinct warning just
+                      once and without the usual explanation.
+
   -input=true         Ask for input for variables if not directly set.
 
   -lock=true          Lock the state file when locking is supported.
@@ -64,7 +64,7 @@ func (c *ShowCommand) Run(args []string) int {
 			planErr = err
 		}
 		if plan == nil {
-			state, err = terraform.ReadState(f)
+			state, err = readStateFile(f)
 			if err != nil {
 				stateErr = err
 			}
@@ -110,7 +110,7 @@ func (c *ShowCommand) Run(args []string) int {
 	}
 
 	if plan != nil {
-		c.Ui.Output(format.Plan(&format.PlanOpts{
+		c.redactUi().Output(format.Plan(&format.PlanOpts{
 			Plan:        plan,
 			Color:       c.Colorize(),
 			ModuleDepth: moduleDepth,
@@ -118,7 +118,7 @@ func (c *ShowCommand) Run(args []string) int {
 		return 0
 	}
 
-	c.Ui.Output(format.State(&format.StateOpts{
+	c.redactUi().Output(format.State(&format.StateOpts{
 		State:       state,
 		Color:       c.Colorize(),
 		ModuleDepth: moduleDepth,
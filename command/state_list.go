@@ -55,6 +55,16 @@ func (c *StateListCommand) Run(args []string) int {
 	}
 
 	filter := &terraform.StateFilter{State: stateReal}
+
+	// Listing everything is common, and only needs the addresses, so we
+	// can skip building the full results.
+	if len(args) == 0 {
+		for _, addr := range filter.Addresses() {
+			c.Ui.Output(addr)
+		}
+		return 0
+	}
+
 	results, err := filter.Filter(args...)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(errStateFilter, err))
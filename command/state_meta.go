@@ -1,8 +1,11 @@
 package command
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"time"
 
 	backendlocal "github.com/hashicorp/terraform/backend/local"
@@ -24,10 +27,20 @@ func (c *StateMeta) State() (state.State, error) {
 	backupPath := c.backupPath
 	stateOutPath := c.statePath
 
+	enc, err := state.EncryptionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	compress, err := state.CompressBackupsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	// use the specified state
 	if c.statePath != "" {
 		realState = &state.LocalState{
-			Path: c.statePath,
+			Path:       c.statePath,
+			Encryption: enc,
 		}
 	} else {
 		// Load the backend
@@ -72,13 +85,36 @@ func (c *StateMeta) State() (state.State, error) {
 
 	// Wrap it for backups
 	realState = &state.BackupState{
-		Real: realState,
-		Path: backupPath,
+		Real:       realState,
+		Path:       backupPath,
+		Encryption: enc,
+		Compress:   compress,
 	}
 
 	return realState, nil
 }
 
+// readStateFile reads a state from the contents of a local state file,
+// decrypting it first with the encryption configured by the environment.
+// Plaintext state files are read as they are.
+func readStateFile(r io.Reader) (*terraform.State, error) {
+	enc, err := state.EncryptionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	raw, err = enc.Decrypt(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return terraform.ReadState(bytes.NewReader(raw))
+}
+
 // filterInstance filters a single instance out of filter results.
 func (c *StateMeta) filterInstance(rs []*terraform.StateFilterResult) (*terraform.StateFilterResult, error) {
 	var result *terraform.StateFilterResult
@@ -60,7 +60,11 @@ func (c *StatePullCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.Ui.Output(buf.String())
+	// WriteState already ends with a newline, and Output adds another. The
+	// result must be byte-for-byte what WriteState produced, or else
+	// ReadState will see it as modified and bump the serial when the state
+	// is pushed back.
+	c.Ui.Output(strings.TrimSuffix(buf.String(), "\n"))
 	return 0
 }
 
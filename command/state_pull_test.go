@@ -36,6 +36,9 @@ func TestStatePull(t *testing.T) {
 	if !strings.Contains(actual, expected) {
 		t.Fatalf("expected:\n%s\n\nto include: %q", actual, expected)
 	}
+	if !strings.HasSuffix(actual, "}\n") {
+		t.Fatalf("output should end with exactly one newline: %q", actual[len(actual)-5:])
+	}
 }
 
 func TestStatePull_noState(t *testing.T) {
@@ -52,7 +52,7 @@ func (c *StatePushCommand) Run(args []string) int {
 	}
 
 	// Read the state
-	sourceState, err := terraform.ReadState(r)
+	sourceState, err := readStateFile(r)
 	if c, ok := r.(io.Closer); ok {
 		// Close the reader if possible right now since we're done with it.
 		c.Close()
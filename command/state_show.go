@@ -96,7 +96,7 @@ func (c *StateShowCommand) Run(args []string) int {
 	// Output
 	config := columnize.DefaultConfig()
 	config.Glue = " = "
-	c.Ui.Output(columnize.Format(output, config))
+	c.redactUi().Output(columnize.Format(output, config))
 	return 0
 }
 
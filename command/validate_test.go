@@ -1,6 +1,8 @@
 package command
 
 import (
+	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -80,7 +82,9 @@ func TestSameResourceMultipleTimesShouldFail(t *testing.T) {
 	if code != 1 {
 		t.Fatalf("Should have failed: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
-	if !strings.HasSuffix(strings.TrimSpace(ui.ErrorWriter.String()), "aws_instance.web: resource repeated multiple times") {
+	main := filepath.Join(testFixturePath("validate-invalid/multiple_resources"), "main.tf")
+	want := fmt.Sprintf("aws_instance.web: resource repeated multiple times (declared at %s:1:10, %s:4:10)", main, main)
+	if !strings.HasSuffix(strings.TrimSpace(ui.ErrorWriter.String()), want) {
 		t.Fatalf("Should have failed: %d\n\n'%s'", code, ui.ErrorWriter.String())
 	}
 }
@@ -8,7 +8,6 @@ import (
 
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/state"
-	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
 
@@ -135,7 +134,7 @@ func (c *WorkspaceNewCommand) Run(args []string) int {
 		return 1
 	}
 
-	s, err := terraform.ReadState(stateFile)
+	s, err := readStateFile(stateFile)
 	if err != nil {
 		c.Ui.Error(err.Error())
 		return 1
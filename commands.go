@@ -33,6 +33,7 @@ func init() {
 		Color:            true,
 		GlobalPluginDirs: globalPluginDirs(),
 		PluginOverrides:  &PluginOverrides,
+		OutputRedaction:  &OutputRedaction,
 		Ui:               Ui,
 	}
 
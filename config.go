@@ -21,6 +21,10 @@ type Config struct {
 
 	DisableCheckpoint          bool `hcl:"disable_checkpoint"`
 	DisableCheckpointSignature bool `hcl:"disable_checkpoint_signature"`
+
+	// RedactPatterns are regular expressions matching values that must be
+	// masked wherever they appear in the CLI's output.
+	RedactPatterns []string `hcl:"redact_patterns"`
 }
 
 // BuiltinConfig is the built-in defaults for the configuration. These
@@ -31,6 +35,9 @@ var BuiltinConfig Config
 // the config file.
 var PluginOverrides command.PluginOverrides
 
+// OutputRedaction holds the compiled redact_patterns from the config file.
+var OutputRedaction command.OutputRedaction
+
 // ConfigFile returns the default path to the configuration file.
 //
 // On Unix-like systems this is the ".terraformrc" file in the home directory.
@@ -105,5 +112,10 @@ func (c1 *Config) Merge(c2 *Config) *Config {
 	result.DisableCheckpoint = c1.DisableCheckpoint || c2.DisableCheckpoint
 	result.DisableCheckpointSignature = c1.DisableCheckpointSignature || c2.DisableCheckpointSignature
 
+	if len(c1.RedactPatterns) > 0 || len(c2.RedactPatterns) > 0 {
+		result.RedactPatterns = append(result.RedactPatterns, c1.RedactPatterns...)
+		result.RedactPatterns = append(result.RedactPatterns, c2.RedactPatterns...)
+	}
+
 	return &result
 }
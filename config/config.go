@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/hcl/token"
 	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform/helper/hilmapstructure"
@@ -83,6 +84,11 @@ type Resource struct {
 	Provider     string
 	DependsOn    []string
 	Lifecycle    ResourceLifecycle
+
+	// Pos is where the resource block begins in the configuration files.
+	// It is only used to help the user find a resource in error messages,
+	// and is the zero value for resources that weren't loaded from a file.
+	Pos token.Pos
 }
 
 // Copy returns a copy of this Resource. Helpful for avoiding shared
@@ -99,6 +105,7 @@ func (r *Resource) Copy() *Resource {
 		Provider:     r.Provider,
 		DependsOn:    make([]string, len(r.DependsOn)),
 		Lifecycle:    *r.Lifecycle.Copy(),
+		Pos:          r.Pos,
 	}
 	for _, p := range r.Provisioners {
 		n.Provisioners = append(n.Provisioners, p.Copy())
@@ -511,23 +518,28 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	// Check that all references to resources are valid
-	resources := make(map[string]*Resource)
-	dupped = make(map[string]struct{})
+	// Check that all references to resources are valid. The position of
+	// every declaration is kept so that a repeated resource can be reported
+	// once with all of the places it is declared.
+	resources := make(map[string]*Resource, len(c.Resources))
+	declared := make(map[string][]token.Pos, len(c.Resources))
+	var repeated []string
 	for _, r := range c.Resources {
-		if _, ok := resources[r.Id()]; ok {
-			if _, ok := dupped[r.Id()]; !ok {
-				dupped[r.Id()] = struct{}{}
-
-				errs = append(errs, fmt.Errorf(
-					"%s: resource repeated multiple times",
-					r.Id()))
-			}
+		id := r.Id()
+		if _, ok := resources[id]; ok && len(declared[id]) == 1 {
+			repeated = append(repeated, id)
 		}
 
-		resources[r.Id()] = r
+		declared[id] = append(declared[id], r.Pos)
+		resources[id] = r
 	}
-	dupped = nil
+	for _, id := range repeated {
+		errs = append(errs, fmt.Errorf(
+			"%s: resource repeated multiple times%s",
+			id, declaredAt(declared[id])))
+	}
+	declared = nil
+	repeated = nil
 
 	// Validate resources
 	for n, r := range resources {
@@ -768,6 +780,23 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// declaredAt describes the given declaration positions for an error
+// message, leaving out any that are unknown. The result is empty if none
+// of the positions are known.
+func declaredAt(positions []token.Pos) string {
+	known := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		if pos.IsValid() {
+			known = append(known, pos.String())
+		}
+	}
+	if len(known) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" (declared at %s)", strings.Join(known, ", "))
+}
+
 // InterpolatedVariables is a helper that returns a mapping of all the interpolated
 // variables within the configuration. This is used to verify references
 // are valid in the Validate step.
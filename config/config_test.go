@@ -11,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform/helper/logging"
 )
@@ -354,6 +355,34 @@ func TestConfigValidate_dupResource(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_dupResourceMany(t *testing.T) {
+	dir := filepath.Join(fixtureDir, "validate-dup-resource-many")
+	c, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = c.Validate()
+	if err == nil {
+		t.Fatal("should not be valid")
+	}
+
+	main := filepath.Join(dir, "main.tf")
+	other := filepath.Join(dir, "other.tf")
+	want := []string{
+		fmt.Sprintf("aws_instance.web: resource repeated multiple times (declared at %s:1:10, %s:7:10, %s:1:10)", main, main, other),
+		fmt.Sprintf("data.aws_ami.base: resource repeated multiple times (declared at %s:5:6, %s:5:6)", main, other),
+		fmt.Sprintf("aws_instance.db: resource repeated multiple times (declared at %s:3:10, %s:3:10)", main, other),
+	}
+	var got []string
+	for _, err := range err.(*multierror.Error).Errors {
+		got = append(got, err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong errors\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
 func TestConfigValidate_ignoreChanges(t *testing.T) {
 	c := testConfig(t, "validate-ignore-changes")
 	if err := c.Validate(); err != nil {
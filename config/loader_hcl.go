@@ -121,6 +121,12 @@ func (t *hclConfigurable) Config() (*Config, error) {
 
 		config.Resources = append(config.Resources, dataResources...)
 		config.Resources = append(config.Resources, managedResources...)
+
+		// The resources only know their line and column, so record which
+		// file they came from too.
+		for _, r := range config.Resources {
+			r.Pos.Filename = t.File
+		}
 	}
 
 	// Build the outputs
@@ -740,6 +746,7 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			Provisioners: []*Provisioner{},
 			DependsOn:    dependsOn,
 			Lifecycle:    ResourceLifecycle{},
+			Pos:          item.Pos(),
 		})
 	}
 
@@ -936,6 +943,7 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			Provider:     provider,
 			DependsOn:    dependsOn,
 			Lifecycle:    lifecycle,
+			Pos:          item.Pos(),
 		})
 	}
 
@@ -153,6 +153,17 @@ func mergeSlice(m1, m2 []merger) []merger {
 	r := make([]merger, len(m1), len(m1)+len(m2))
 	copy(r, m1)
 
+	// Index the originals by name so that finding the one to override
+	// doesn't need a scan of m1 for every item in m2. The first original
+	// with a given name is the one that is overridden.
+	index := make(map[string]int, len(m1))
+	for i, v := range m1 {
+		name := v.mergerName()
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
 	m := map[string]struct{}{}
 	for _, v2 := range m2 {
 		// If we already saw it, just append it because its a
@@ -166,13 +177,11 @@ func mergeSlice(m1, m2 []merger) []merger {
 
 		// Find an original to override
 		var original merger
-		originalIndex := -1
-		for i, v := range m1 {
-			if v.mergerName() == name {
-				originalIndex = i
-				original = v
-				break
-			}
+		originalIndex, ok := index[name]
+		if ok {
+			original = m1[originalIndex]
+		} else {
+			originalIndex = -1
 		}
 
 		var v merger
@@ -0,0 +1,9 @@
+resource "aws_instance" "web" {}
+
+resource "aws_instance" "db" {}
+
+data "aws_ami" "base" {}
+
+resource "aws_instance" "web" {}
+
+resource "aws_instance" "unique" {}
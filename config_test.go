@@ -92,6 +92,21 @@ func TestConfig_Merge(t *testing.T) {
 	}
 }
 
+func TestConfig_Merge_redactPatterns(t *testing.T) {
+	c1 := &Config{
+		RedactPatterns: []string{"AKIA[0-9A-Z]{16}"},
+	}
+	c2 := &Config{
+		RedactPatterns: []string{"hunter\\d"},
+	}
+
+	expected := []string{"AKIA[0-9A-Z]{16}", "hunter\\d"}
+	actual := c1.Merge(c2).RedactPatterns
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestConfig_Merge_disableCheckpoint(t *testing.T) {
 	c1 := &Config{
 		DisableCheckpoint: true,
@@ -102,18 +102,50 @@ func (g *AcyclicGraph) TransitiveReduction() {
 	// For each v-prime reachable from v, remove the edge (u, v-prime).
 	defer g.debug.BeginOperation("TransitiveReduction", "").End("")
 
+	// Each vertex can walk most of a large graph, so rather than using
+	// DepthFirstWalk, which sorts the targets of every vertex it visits,
+	// we walk in whatever order the edges come in and reuse the same
+	// stack and seen map for every vertex. The order doesn't change
+	// which edges are removed. Each walk starts from the targets of u's
+	// targets, so any target of u that it reaches is redundant.
+	seen := make(map[interface{}]struct{})
+	var stack []Vertex
+	push := func(v Vertex) {
+		if s := g.DownEdges(v); s != nil {
+			for _, t := range s.m {
+				stack = append(stack, t)
+			}
+		}
+	}
+
 	for _, u := range g.Vertices() {
+		op := g.debug.BeginOperation(typeDepthFirstWalk, "")
+
 		uTargets := g.DownEdges(u)
-		vs := AsVertexList(g.DownEdges(u))
+		for k := range seen {
+			delete(seen, k)
+		}
+		for _, v := range AsVertexList(uTargets) {
+			push(v)
+		}
+
+		for len(stack) > 0 {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
 
-		g.DepthFirstWalk(vs, func(v Vertex, d int) error {
-			shared := uTargets.Intersection(g.DownEdges(v))
-			for _, vPrime := range AsVertexList(shared) {
-				g.RemoveEdge(BasicEdge(u, vPrime))
+			code := hashcode(v)
+			if _, ok := seen[code]; ok {
+				continue
 			}
+			seen[code] = struct{}{}
+
+			if uTargets.Include(v) {
+				g.RemoveEdge(BasicEdge(u, v))
+			}
+			push(v)
+		}
 
-			return nil
-		})
+		op.End("")
 	}
 }
 
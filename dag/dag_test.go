@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"os"
 	"reflect"
 	"strings"
@@ -106,6 +107,57 @@ func TestAyclicGraphTransReduction_more(t *testing.T) {
 	}
 }
 
+func TestAcyclicGraphTransReduction_naive(t *testing.T) {
+	// TransitiveReduction walks the graph in no particular order, so check
+	// that it removes exactly the same edges as walking it in order does.
+	for seed := int64(0); seed < 50; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		n := 2 + r.Intn(40)
+		density := r.Float64()
+
+		var g, naive AcyclicGraph
+		for i := 0; i < n; i++ {
+			g.Add(i)
+			naive.Add(i)
+		}
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				if r.Float64() < density {
+					g.Connect(BasicEdge(i, j))
+					naive.Connect(BasicEdge(i, j))
+				}
+			}
+		}
+
+		g.TransitiveReduction()
+		testTransitiveReductionNaive(&naive)
+
+		actual := strings.TrimSpace(g.String())
+		expected := strings.TrimSpace(naive.String())
+		if actual != expected {
+			t.Fatalf("seed %d: wrong reduction\n\ngot:\n%s\n\nwant:\n%s", seed, actual, expected)
+		}
+	}
+}
+
+// testTransitiveReductionNaive is the transitive reduction as it was
+// implemented before it was optimized, using DepthFirstWalk.
+func testTransitiveReductionNaive(g *AcyclicGraph) {
+	for _, u := range g.Vertices() {
+		uTargets := g.DownEdges(u)
+		vs := AsVertexList(g.DownEdges(u))
+
+		g.DepthFirstWalk(vs, func(v Vertex, d int) error {
+			shared := uTargets.Intersection(g.DownEdges(v))
+			for _, vPrime := range AsVertexList(shared) {
+				g.RemoveEdge(BasicEdge(u, vPrime))
+			}
+
+			return nil
+		})
+	}
+}
+
 func TestAcyclicGraphValidate(t *testing.T) {
 	var g AcyclicGraph
 	g.Add(1)
@@ -53,6 +53,12 @@ var (
 	// Shadow graph. This is already on by default. Disabling it will be
 	// allowed for awhile in order for it to not block operations.
 	X_shadow = newBasicID("shadow", "SHADOW", false)
+
+	// Stream plan rendering. The plan command writes out each resource
+	// change as soon as it is formatted, rather than formatting the whole
+	// plan first, so that huge plans don't need the whole rendered text in
+	// memory at once. The output is the same either way.
+	X_streamPlanRender = newBasicID("stream-plan-render", "STREAM_PLAN_RENDER", false)
 )
 
 // Global variables this package uses because we are a package
@@ -73,6 +79,7 @@ func init() {
 	// The list of all experiments, update this when an experiment is added.
 	All = []ID{
 		X_shadow,
+		X_streamPlanRender,
 		x_force,
 	}
 
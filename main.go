@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -185,6 +186,16 @@ func wrappedMain() int {
 	PluginOverrides.Providers = config.Providers
 	PluginOverrides.Provisioners = config.Provisioners
 
+	for _, raw := range config.RedactPatterns {
+		pattern, err := regexp.Compile(raw)
+		if err != nil {
+			Ui.Error(fmt.Sprintf(
+				"Error loading CLI configuration: \n\ninvalid redact_patterns entry %q: %s", raw, err))
+			return 1
+		}
+		OutputRedaction.Patterns = append(OutputRedaction.Patterns, pattern)
+	}
+
 	exitCode, err := cliRunner.Run()
 	if err != nil {
 		Ui.Error(fmt.Sprintf("Error executing CLI: %s", err.Error()))
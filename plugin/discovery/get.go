@@ -3,13 +3,17 @@ package discovery
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/html"
 
@@ -58,6 +62,16 @@ type ProviderInstaller struct {
 
 	// Skip checksum and signature verification
 	SkipVerify bool
+
+	// Progress, if set, is called periodically while a provider's release
+	// archive is downloading, with the number of bytes downloaded so far and
+	// the total size of the archive, or -1 if the server didn't say. It is
+	// called at most once per progressInterval for each download, so short
+	// downloads may not report at all.
+	//
+	// When several providers are installed at once by GetAll, Progress may
+	// be called concurrently for different providers.
+	Progress func(provider string, v Version, downloaded, total int64)
 }
 
 // Get is part of an implementation of type Installer, and attempts to download
@@ -102,66 +116,337 @@ func (i *ProviderInstaller) Get(provider string, req Constraints) (PluginMeta, e
 	for _, v := range versions {
 		url := i.providerURL(provider, v.String())
 
+		var sha256 string
 		if !i.SkipVerify {
-			sha256, err := i.getProviderChecksum(provider, v.String())
+			sha256, err = i.getProviderChecksum(provider, v.String())
 			if err != nil {
 				return PluginMeta{}, err
 			}
-
-			// add the checksum parameter for go-getter to verify the download for us.
-			if sha256 != "" {
-				url = url + "?checksum=sha256:" + sha256
-			}
 		}
 
 		log.Printf("[DEBUG] fetching provider info for %s version %s", provider, v)
 		if checkPlugin(url, i.PluginProtocolVersion) {
 			log.Printf("[DEBUG] getting provider %q version %q at %s", provider, v, url)
-			err := getter.Get(i.Dir, url)
-			if err != nil {
-				return PluginMeta{}, err
-			}
+			return i.install(provider, v, url, sha256)
+		}
 
-			// Find what we just installed
-			// (This is weird, because go-getter doesn't directly return
-			//  information about what was extracted, and we just extracted
-			//  the archive directly into a shared dir here.)
-			log.Printf("[DEBUG] looking for the %s %s plugin we just installed", provider, v)
-			metas := FindPlugins("provider", []string{i.Dir})
-			log.Printf("[DEBUG] all plugins found %#v", metas)
-			metas, _ = metas.ValidateVersions()
-			metas = metas.WithName(provider).WithVersion(v)
-			log.Printf("[DEBUG] filtered plugins %#v", metas)
-			if metas.Count() == 0 {
-				// This should never happen. Suggests that the release archive
-				// contains an executable file whose name doesn't match the
-				// expected convention.
-				return PluginMeta{}, fmt.Errorf(
-					"failed to find installed plugin version %s; this is a bug in Terraform and should be reported",
-					v,
-				)
-			}
+		log.Printf("[INFO] incompatible ProtocolVersion for %s version %s", provider, v)
+	}
+
+	return PluginMeta{}, ErrorNoVersionCompatible
+}
+
+// install downloads the given version of a provider from the given URL and
+// moves its executable into the installer's directory. If sha256 isn't
+// empty, the release archive must have that checksum.
+//
+// The release archive is extracted into a temporary directory first, so that
+// a failed download never leaves a partial plugin behind for FindPlugins to
+// discover, and so that several providers can be installed into the same
+// directory at once.
+func (i *ProviderInstaller) install(provider string, v Version, url, sha256 string) (PluginMeta, error) {
+	if err := os.MkdirAll(i.Dir, 0755); err != nil {
+		return PluginMeta{}, err
+	}
+	stageDir, err := ioutil.TempDir(i.Dir, ".install-"+provider+"-")
+	if err != nil {
+		return PluginMeta{}, err
+	}
+	defer os.RemoveAll(stageDir)
+
+	// The archive is downloaded separately from stageDir, where its name
+	// could be mistaken for a plugin, and then given to go-getter as a
+	// local file so that go-getter still verifies and extracts it.
+	downloadDir, err := ioutil.TempDir(i.Dir, ".download-"+provider+"-")
+	if err != nil {
+		return PluginMeta{}, err
+	}
+	defer os.RemoveAll(downloadDir)
+
+	archive := filepath.Join(downloadDir, i.providerFileName(provider, v.String()))
+	if err := i.download(provider, v, url, archive); err != nil {
+		return PluginMeta{}, err
+	}
+
+	src, err := filepath.Abs(archive)
+	if err != nil {
+		return PluginMeta{}, err
+	}
+	if sha256 != "" {
+		// add the checksum parameter for go-getter to verify the download for us.
+		src = src + "?checksum=sha256:" + sha256
+	}
+	if err := getter.Get(stageDir, src); err != nil {
+		return PluginMeta{}, err
+	}
+
+	// Find what we just installed
+	// (This is weird, because go-getter doesn't directly return
+	//  information about what was extracted.)
+	log.Printf("[DEBUG] looking for the %s %s plugin we just installed", provider, v)
+	metas := FindPlugins("provider", []string{stageDir})
+	log.Printf("[DEBUG] all plugins found %#v", metas)
+	metas, _ = metas.ValidateVersions()
+	metas = metas.WithName(provider).WithVersion(v)
+	log.Printf("[DEBUG] filtered plugins %#v", metas)
+	if metas.Count() == 0 {
+		// This should never happen. Suggests that the release archive
+		// contains an executable file whose name doesn't match the
+		// expected convention.
+		return PluginMeta{}, fmt.Errorf(
+			"failed to find installed plugin version %s; this is a bug in Terraform and should be reported",
+			v,
+		)
+	}
+
+	if metas.Count() > 1 {
+		// This should also never happen, and suggests that a
+		// particular version was re-released with a different
+		// executable filename. We consider releases as immutable, so
+		// this is an error.
+		return PluginMeta{}, fmt.Errorf(
+			"multiple plugins installed for version %s; this is a bug in Terraform and should be reported",
+			v,
+		)
+	}
+
+	// By now we know we have exactly one meta, and so "Newest" will
+	// return that one.
+	meta := metas.Newest()
+	dest, err := filepath.Abs(filepath.Join(i.Dir, filepath.Base(meta.Path)))
+	if err != nil {
+		return PluginMeta{}, err
+	}
+	if err := os.Rename(meta.Path, dest); err != nil {
+		return PluginMeta{}, err
+	}
+	meta.Path = dest
+	return meta, nil
+}
+
+// download fetches the given URL into a new file at path, calling
+// i.Progress as it goes if it is set.
+//
+// The data is written to a ".part" file next to path until it is complete.
+// If the connection fails part way through, the download is retried up to
+// downloadRetries times, asking the server for only the part that is still
+// missing. The ".part" file is removed whether or not the download
+// eventually succeeds.
+func (i *ProviderInstaller) download(provider string, v Version, url, path string) error {
+	part := path + ".part"
+	defer os.Remove(part)
+
+	for attempt := 0; ; attempt++ {
+		retry, err := i.downloadPart(provider, v, url, part)
+		if err == nil {
+			break
+		}
+		if !retry || attempt >= downloadRetries {
+			return fmt.Errorf("error downloading %s: %s", url, err)
+		}
+		log.Printf("[WARN] download of %s failed, retrying: %s", url, err)
+		time.Sleep(downloadRetryDelay)
+	}
+
+	return os.Rename(part, path)
+}
+
+// downloadRetries is the number of times that ProviderInstaller retries
+// an interrupted download, waiting downloadRetryDelay before each retry.
+var (
+	downloadRetries    = 3
+	downloadRetryDelay = 2 * time.Second
+)
+
+// downloadPart makes one attempt to download the given URL into the file at
+// path, continuing from the end of what is already in the file if the
+// server supports range requests.
+//
+// If it returns an error, retry is true if a later attempt might succeed.
+func (i *ProviderInstaller) downloadPart(provider string, v Version, url, path string) (retry bool, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
 
-			if metas.Count() > 1 {
-				// This should also never happen, and suggests that a
-				// particular version was re-released with a different
-				// executable filename. We consider releases as immutable, so
-				// this is an error.
-				return PluginMeta{}, fmt.Errorf(
-					"multiple plugins installed for version %s; this is a bug in Terraform and should be reported",
-					v,
-				)
+	total := resp.ContentLength
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		// Either this is the first attempt, or the server ignored the range
+		// and is sending the whole file again.
+		if offset > 0 {
+			log.Printf("[DEBUG] %s doesn't support resuming downloads, starting again", url)
+			if err := f.Truncate(0); err != nil {
+				return false, err
 			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return false, err
+			}
+			offset = 0
+		}
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp.Header.Get("Content-Range")) == offset:
+		log.Printf("[DEBUG] resuming download of %s from byte %d", url, offset)
+		if total >= 0 {
+			total += offset
+		}
+	case resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The server doesn't agree about what we already have, so the next
+		// attempt starts from the beginning.
+		if err := f.Truncate(0); err != nil {
+			return false, err
+		}
+		return true, fmt.Errorf("unexpected range in response: %s", resp.Status)
+	case resp.StatusCode >= 500:
+		return true, errors.New(resp.Status)
+	default:
+		return false, errors.New(resp.Status)
+	}
 
-			// By now we know we have exactly one meta, and so "Newest" will
-			// return that one.
-			return metas.Newest(), nil
+	body := &readErrorRecorder{r: resp.Body}
+	var src io.Reader = body
+	if i.Progress != nil {
+		src = &progressReader{
+			r:    body,
+			read: offset,
+			last: time.Now(),
+			report: func(downloaded int64) {
+				i.Progress(provider, v, downloaded, total)
+			},
 		}
+	}
 
-		log.Printf("[INFO] incompatible ProtocolVersion for %s version %s", provider, v)
+	if _, err := io.Copy(f, src); err != nil {
+		// Only a failure to read from the connection is worth retrying.
+		return body.err != nil, err
 	}
+	return false, f.Close()
+}
 
-	return PluginMeta{}, ErrorNoVersionCompatible
+// contentRangeStart returns the position of the first byte in the given
+// Content-Range header value, or -1 if it isn't a valid byte range.
+func contentRangeStart(header string) int64 {
+	if !strings.HasPrefix(header, "bytes ") {
+		return -1
+	}
+	header = strings.TrimPrefix(header, "bytes ")
+	dash := strings.Index(header, "-")
+	if dash < 0 {
+		return -1
+	}
+	start, err := strconv.ParseInt(header[:dash], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// readErrorRecorder remembers the first error other than io.EOF returned
+// by the reader it wraps.
+type readErrorRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// progressInterval is the shortest time between two calls to
+// ProviderInstaller.Progress for the same download.
+var progressInterval = 2 * time.Second
+
+// progressReader counts the bytes read through it, and passes the count to
+// report once every progressInterval.
+type progressReader struct {
+	r      io.Reader
+	read   int64
+	last   time.Time
+	report func(read int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if now := time.Now(); n > 0 && now.Sub(r.last) >= progressInterval {
+		r.last = now
+		r.report(r.read)
+	}
+	return n, err
+}
+
+// GetAll calls Get on the given installer for each of the given plugins,
+// running at most parallelism calls at once. A parallelism of less than one
+// is treated as one.
+//
+// The result has a PluginMeta for each plugin that was installed and an
+// error for each plugin that wasn't. A failure to install one plugin does
+// not prevent or interrupt the installation of any of the others.
+func GetAll(i Installer, reqs PluginRequirements, parallelism int) (map[string]PluginMeta, map[string]error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	type result struct {
+		name string
+		meta PluginMeta
+		err  error
+	}
+	names := make(chan string)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for n := 0; n < parallelism && n < len(reqs); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				meta, err := i.Get(name, reqs[name].Versions)
+				results <- result{name, meta, err}
+			}
+		}()
+	}
+	go func() {
+		for name := range reqs {
+			names <- name
+		}
+		close(names)
+		wg.Wait()
+		close(results)
+	}()
+
+	metas := make(map[string]PluginMeta)
+	errs := make(map[string]error)
+	for r := range results {
+		if r.err != nil {
+			errs[r.name] = r.err
+			continue
+		}
+		metas[r.name] = r.meta
+	}
+	return metas, errs
 }
 
 func (i *ProviderInstaller) PurgeUnused(used map[string]PluginMeta) (PluginMetaSet, error) {
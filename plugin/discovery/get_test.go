@@ -2,6 +2,9 @@ package discovery
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,8 +14,11 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const testProviderFile = "test provider binary"
@@ -48,13 +54,16 @@ func testChecksumHandler(w http.ResponseWriter, r *http.Request) {
 
 // returns a 200 for a valid provider url, using the patch number for the
 // plugin protocol version.
+//
+// The same releases are served for any provider name that the handler is
+// registered for, so that tests can install several different providers.
 func testHandler(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "/terraform-provider-test/" {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) == 3 && parts[2] == "" {
 		testListingHandler(w, r)
 		return
 	}
 
-	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) != 4 {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
@@ -62,7 +71,7 @@ func testHandler(w http.ResponseWriter, r *http.Request) {
 
 	filename := parts[3]
 
-	reg := regexp.MustCompile(`(terraform-provider-test)_(\d).(\d).(\d)_([^_]+)_([^._]+).zip`)
+	reg := regexp.MustCompile(`(terraform-provider-[a-z0-9]+)_(\d).(\d).(\d)_([^_]+)_([^._]+).zip`)
 
 	fileParts := reg.FindStringSubmatch(filename)
 	if len(fileParts) != 7 {
@@ -72,22 +81,96 @@ func testHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set(protocolVersionHeader, fileParts[4])
 
-	// write a dummy file
-	z := zip.NewWriter(w)
+	content := testProviderFile
+	if fileParts[1] == "terraform-provider-"+testLargeProvider {
+		content = strings.Repeat("x", testLargeProviderSize)
+	}
+
+	// write a dummy file, uncompressed so that the archive is about as
+	// large as the file in it
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
 	fn := fmt.Sprintf("%s_v%s.%s.%s_x%s", fileParts[1], fileParts[2], fileParts[3], fileParts[4], fileParts[4])
-	f, err := z.Create(fn)
+	f, err := z.CreateHeader(&zip.FileHeader{Name: fn, Method: zip.Store})
 	if err != nil {
 		panic(err)
 	}
-	io.WriteString(f, testProviderFile)
+	io.WriteString(f, content)
 	z.Close()
+
+	http.ServeContent(w, r, filename, time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+// testLargeProvider is served by testHandler with an executable of
+// testLargeProviderSize bytes, for tests of download progress.
+const (
+	testLargeProvider     = "large"
+	testLargeProviderSize = 4 << 20
+)
+
+// testFlakyHandler is like testHandler, but fails each download of a
+// release archive that doesn't ask for a range, after sending half of it.
+// If ranges is false, it then also ignores the range in the next request
+// and sends the whole archive.
+//
+// If always is true then no download of an archive ever succeeds.
+func testFlakyHandler(ranges, always bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && strings.HasSuffix(r.URL.Path, ".zip") {
+			testFlakyRequests.Lock()
+			testFlakyRequests.ranges[r.URL.Path] = append(testFlakyRequests.ranges[r.URL.Path], r.Header.Get("Range"))
+			testFlakyRequests.Unlock()
+		}
+
+		if r.Method != "GET" || !strings.HasSuffix(r.URL.Path, ".zip") || (r.Header.Get("Range") != "" && !always) {
+			if !ranges {
+				r.Header.Del("Range")
+			}
+			testHandler(w, r)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		testHandler(rec, r)
+		body := rec.Body.Bytes()
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body[:len(body)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// testFlakyRequests records the Range header of each request for a release
+// archive served by testFlakyHandler, by path.
+var testFlakyRequests = struct {
+	sync.Mutex
+	ranges map[string][]string
+}{ranges: make(map[string][]string)}
+
+// testSlowProviders are served by testSlowHandler.
+var testSlowProviders = []string{"slowa", "slowb", "slowc"}
+
+// testSlowHandler is like testHandler, but delays each response to simulate
+// the round trip to a distant releases server.
+func testSlowHandler(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(20 * time.Millisecond)
+	testHandler(w, r)
 }
 
 func testReleaseServer() *httptest.Server {
 	handler := http.NewServeMux()
 	handler.HandleFunc("/terraform-provider-test/", testHandler)
+	handler.HandleFunc("/terraform-provider-"+testLargeProvider+"/", testHandler)
+	handler.HandleFunc("/terraform-provider-flaky/", testFlakyHandler(true, false))
+	handler.HandleFunc("/terraform-provider-flakynorange/", testFlakyHandler(false, false))
+	handler.HandleFunc("/terraform-provider-broken/", testFlakyHandler(true, true))
 	handler.HandleFunc("/terraform-provider-template/", testChecksumHandler)
 	handler.HandleFunc("/terraform-provider-badsig/", testChecksumHandler)
+	for _, name := range testSlowProviders {
+		handler.HandleFunc("/terraform-provider-"+name+"/", testSlowHandler)
+	}
 
 	return httptest.NewServer(handler)
 }
@@ -202,6 +285,382 @@ func TestProviderInstallerGet(t *testing.T) {
 		t.Fatalf("test provider contains: %q", f)
 	}
 
+	// the temporary directory the provider was unzipped into should be gone
+	infos, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		t.Fatalf("wrong files in plugin dir: %q", names)
+	}
+}
+
+func TestProviderInstallerGet_progress(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	defer func(old time.Duration) { progressInterval = old }(progressInterval)
+	progressInterval = 0
+
+	type call struct {
+		downloaded, total int64
+	}
+	var calls []call
+	i := &ProviderInstaller{
+		Dir:                   tmpDir,
+		PluginProtocolVersion: 3,
+		SkipVerify:            true,
+		Progress: func(provider string, v Version, downloaded, total int64) {
+			if provider != testLargeProvider || v.String() != "1.2.3" {
+				t.Errorf("progress reported for %s %s", provider, v)
+			}
+			calls = append(calls, call{downloaded, total})
+		},
+	}
+
+	meta, err := i.Get(testLargeProvider, AllVersions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(meta.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != testLargeProviderSize {
+		t.Errorf("wrong size for installed provider %d; want %d", info.Size(), testLargeProviderSize)
+	}
+
+	if len(calls) == 0 {
+		t.Fatal("no progress reported")
+	}
+	var last int64
+	for _, c := range calls {
+		if c.downloaded <= last {
+			t.Fatalf("progress went from %d to %d bytes", last, c.downloaded)
+		}
+		last = c.downloaded
+	}
+	if total := calls[0].total; total < testLargeProviderSize {
+		t.Errorf("wrong total size %d; want at least %d", total, testLargeProviderSize)
+	}
+	if last != calls[0].total {
+		t.Errorf("progress stopped at %d of %d bytes", last, calls[0].total)
+	}
+}
+
+func TestProviderInstallerGet_progressThrottled(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The default interval is much longer than a download from the test
+	// server takes, so nothing is reported.
+	i := &ProviderInstaller{
+		Dir:                   tmpDir,
+		PluginProtocolVersion: 3,
+		SkipVerify:            true,
+		Progress: func(provider string, v Version, downloaded, total int64) {
+			t.Errorf("unexpected progress %d of %d bytes", downloaded, total)
+		},
+	}
+	if _, err := i.Get(testLargeProvider, AllVersions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProviderInstallerInstall_badChecksum(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	i := &ProviderInstaller{
+		Dir:                   tmpDir,
+		PluginProtocolVersion: 3,
+	}
+	v := VersionStr("1.2.3").MustParse()
+	_, err = i.install("test", v, i.providerURL("test", v.String()), strings.Repeat("0", 64))
+	if err == nil {
+		t.Fatal("install succeeded with the wrong checksum")
+	}
+	if !strings.Contains(err.Error(), "Checksums did not match") {
+		t.Errorf("wrong error: %s", err)
+	}
+
+	// nothing should be left behind in the plugin dir
+	infos, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		t.Fatalf("wrong files in plugin dir: %q", names)
+	}
+}
+
+func TestProviderInstallerInstall_resume(t *testing.T) {
+	defer func(old time.Duration) { downloadRetryDelay = old }(downloadRetryDelay)
+	downloadRetryDelay = 0
+
+	for _, provider := range []string{"flaky", "flakynorange"} {
+		t.Run(provider, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "tf-plugin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			i := &ProviderInstaller{
+				Dir:                   tmpDir,
+				PluginProtocolVersion: 3,
+			}
+			v := VersionStr("1.2.3").MustParse()
+			url := i.providerURL(provider, v.String())
+
+			// The checksum makes go-getter verify that the pieces of the
+			// archive were put back together correctly.
+			rec := httptest.NewRecorder()
+			testHandler(rec, httptest.NewRequest("GET", url, nil))
+			sum := sha256.Sum256(rec.Body.Bytes())
+
+			testFlakyRequests.Lock()
+			delete(testFlakyRequests.ranges, strings.TrimPrefix(url, releaseHost))
+			testFlakyRequests.Unlock()
+
+			meta, err := i.install(provider, v, url, hex.EncodeToString(sum[:]))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The second request should have asked for the half of the
+			// archive that the first one didn't get.
+			testFlakyRequests.Lock()
+			gotRanges := testFlakyRequests.ranges[strings.TrimPrefix(url, releaseHost)]
+			testFlakyRequests.Unlock()
+			wantRanges := []string{"", fmt.Sprintf("bytes=%d-", rec.Body.Len()/2)}
+			if !reflect.DeepEqual(gotRanges, wantRanges) {
+				t.Errorf("wrong ranges requested\ngot:  %#v\nwant: %#v", gotRanges, wantRanges)
+			}
+			got, err := ioutil.ReadFile(meta.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != testProviderFile {
+				t.Fatalf("test provider contains: %q", got)
+			}
+
+			infos, err := ioutil.ReadDir(tmpDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(infos) != 1 {
+				var names []string
+				for _, info := range infos {
+					names = append(names, info.Name())
+				}
+				t.Fatalf("wrong files in plugin dir: %q", names)
+			}
+		})
+	}
+}
+
+func TestProviderInstallerInstall_resumeFailed(t *testing.T) {
+	defer func(old time.Duration) { downloadRetryDelay = old }(downloadRetryDelay)
+	downloadRetryDelay = 0
+
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	i := &ProviderInstaller{
+		Dir:                   tmpDir,
+		PluginProtocolVersion: 3,
+		SkipVerify:            true,
+	}
+	v := VersionStr("1.2.3").MustParse()
+	_, err = i.install("broken", v, i.providerURL("broken", v.String()), "")
+	if err == nil {
+		t.Fatal("install succeeded from a server that never finishes")
+	}
+
+	// the partial download should be gone along with everything else
+	infos, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		t.Fatalf("wrong files in plugin dir: %q", names)
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	cases := map[string]int64{
+		"bytes 100-199/200": 100,
+		"bytes 0-99/*":      0,
+		"bytes */200":       -1,
+		"items 100-199/200": -1,
+		"":                  -1,
+	}
+
+	for header, want := range cases {
+		if got := contentRangeStart(header); got != want {
+			t.Errorf("%q: got %d; want %d", header, got, want)
+		}
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	installer := callbackInstaller(func(provider string, req Constraints) (PluginMeta, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if provider == "broken" {
+			return PluginMeta{}, ErrorNoSuchProvider
+		}
+		return PluginMeta{Name: provider, Version: "1.0.0"}, nil
+	})
+
+	reqs := PluginRequirements{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "broken"} {
+		reqs[name] = &PluginConstraints{Versions: AllVersions}
+	}
+
+	metas, errs := GetAll(installer, reqs, 2)
+	if len(metas) != 5 {
+		t.Errorf("wrong number of plugins installed %d; want 5\n%#v", len(metas), metas)
+	}
+	for name, meta := range metas {
+		if meta.Name != name {
+			t.Errorf("wrong meta for %s: %#v", name, meta)
+		}
+	}
+	if len(errs) != 1 || errs["broken"] != ErrorNoSuchProvider {
+		t.Errorf("wrong errors: %#v", errs)
+	}
+	if maxRunning != 2 {
+		t.Errorf("%d plugins installed at once; want 2", maxRunning)
+	}
+}
+
+func TestGetAllProviderInstaller(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	i := &ProviderInstaller{
+		Dir: tmpDir,
+		PluginProtocolVersion: 3,
+		SkipVerify:            true,
+	}
+	reqs := PluginRequirements{
+		"nonexist": &PluginConstraints{Versions: AllVersions},
+	}
+	for _, name := range testSlowProviders {
+		reqs[name] = &PluginConstraints{Versions: AllVersions}
+	}
+
+	metas, errs := GetAll(i, reqs, len(reqs))
+	if len(errs) != 1 || errs["nonexist"] != ErrorNoSuchProvider {
+		t.Errorf("wrong errors: %#v", errs)
+	}
+
+	// Every provider that could be installed must have been installed
+	// completely, regardless of the failure.
+	found := FindPlugins("provider", []string{tmpDir})
+	if found.Count() != len(testSlowProviders) {
+		t.Fatalf("wrong plugins installed: %#v", found)
+	}
+	for _, name := range testSlowProviders {
+		meta, ok := metas[name]
+		if !ok {
+			t.Errorf("%s was not installed", name)
+			continue
+		}
+		if !found.Has(meta) {
+			t.Errorf("%s was installed as %#v, which FindPlugins doesn't report", name, meta)
+		}
+		f, err := ioutil.ReadFile(meta.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(f) != testProviderFile {
+			t.Errorf("%s contains: %q", name, f)
+		}
+	}
+}
+
+// BenchmarkGetAll installs several providers from a releases server that is
+// slow to respond, to show the effect of installing them in parallel.
+func BenchmarkGetAll(b *testing.B) {
+	reqs := PluginRequirements{}
+	for _, name := range testSlowProviders {
+		reqs[name] = &PluginConstraints{Versions: AllVersions}
+	}
+
+	for _, parallelism := range []int{1, len(reqs)} {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				tmpDir, err := ioutil.TempDir("", "tf-plugin")
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				i := &ProviderInstaller{
+					Dir: tmpDir,
+					PluginProtocolVersion: 3,
+					SkipVerify:            true,
+				}
+				_, errs := GetAll(i, reqs, parallelism)
+				os.RemoveAll(tmpDir)
+				if len(errs) != 0 {
+					b.Fatalf("unexpected errors: %#v", errs)
+				}
+			}
+		})
+	}
+}
+
+type callbackInstaller func(provider string, req Constraints) (PluginMeta, error)
+
+func (cb callbackInstaller) Get(provider string, req Constraints) (PluginMeta, error) {
+	return cb(provider, req)
+}
+
+func (cb callbackInstaller) PurgeUnused(map[string]PluginMeta) (PluginMetaSet, error) {
+	return make(PluginMetaSet), nil
 }
 
 func TestProviderInstallerPurgeUnused(t *testing.T) {
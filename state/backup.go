@@ -1,6 +1,7 @@
 package state
 
 import (
+	"os"
 	"sync"
 
 	"github.com/hashicorp/terraform/terraform"
@@ -15,6 +16,14 @@ type BackupState struct {
 	Real State
 	Path string
 
+	// Encryption, if set, is used to encrypt the backup file in the same
+	// way as LocalState.Encryption.
+	Encryption StateEncryption
+
+	// Compress, if set, causes the backup file to be compressed with gzip,
+	// in which case CompressedBackupExtension is added to Path.
+	Compress bool
+
 	done bool
 }
 
@@ -74,10 +83,26 @@ func (s *BackupState) backup() error {
 	// purposes, but we don't need a backup or lock if the state is empty, so
 	// skip this with a nil state.
 	if state != nil {
-		ls := &LocalState{Path: s.Path}
+		path := s.Path
+		if s.Compress {
+			path += CompressedBackupExtension
+		}
+
+		ls := &LocalState{Path: path, Encryption: s.Encryption, Compress: s.Compress}
 		if err := ls.WriteState(state); err != nil {
 			return err
 		}
+
+		// Any backup in the other format is older than the one just
+		// written, so remove it rather than leave it to be restored by
+		// mistake.
+		stale := s.Path + CompressedBackupExtension
+		if s.Compress {
+			stale = s.Path
+		}
+		if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 
 	s.done = true
@@ -1,10 +1,14 @@
 package state
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
 )
 
 func TestBackupState_locker(t *testing.T) {
@@ -63,3 +67,85 @@ func TestBackupStateRace(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestBackupState_compressed(t *testing.T) {
+	enc, err := NewAESGCMEncryption(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, enc := range []StateEncryption{nil, enc} {
+		dir, err := ioutil.TempDir("", "tf")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer os.RemoveAll(dir)
+
+		ls := testLocalState(t)
+		defer os.Remove(ls.Path)
+		original := ls.State()
+
+		path := filepath.Join(dir, "terraform.tfstate.backup")
+		bs := &BackupState{
+			Real:       ls,
+			Path:       path,
+			Encryption: enc,
+			Compress:   true,
+		}
+		next := original.DeepCopy()
+		next.RootModule().Outputs["bar"] = &terraform.OutputState{Type: "string", Value: "baz"}
+		if err := bs.WriteState(next); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("uncompressed backup was written: %v", err)
+		}
+
+		// The backup reads back as the state from before the write.
+		backup := &LocalState{Path: path + CompressedBackupExtension, Encryption: enc}
+		if err := backup.RefreshState(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !backup.State().Equal(original) {
+			t.Fatalf("wrong backup state\ngot:\n%s\nwant:\n%s", backup.State(), original)
+		}
+	}
+}
+
+func TestBackupState_compressedSwitch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "terraform.tfstate.backup")
+	compressedPath := path + CompressedBackupExtension
+
+	// Turning compression on and then off again must each time leave only
+	// the backup that was just written.
+	for _, tc := range []struct {
+		Compress      bool
+		Written, Gone string
+	}{
+		{false, path, compressedPath},
+		{true, compressedPath, path},
+		{false, path, compressedPath},
+	} {
+		ls := testLocalState(t)
+		defer os.Remove(ls.Path)
+
+		bs := &BackupState{Real: ls, Path: path, Compress: tc.Compress}
+		if err := bs.WriteState(ls.State()); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if _, err := os.Stat(tc.Written); err != nil {
+			t.Fatalf("compress=%t: backup was not written: %s", tc.Compress, err)
+		}
+		if _, err := os.Stat(tc.Gone); !os.IsNotExist(err) {
+			t.Fatalf("compress=%t: stale backup %s was not removed: %v", tc.Compress, tc.Gone, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// StateBackupCompressEnvVar is the name of the environment variable that
+// turns on compression of state backups. Its value is parsed with
+// strconv.ParseBool, so "1" or "true" turns compression on.
+const StateBackupCompressEnvVar = "TF_STATE_BACKUP_COMPRESS"
+
+// CompressedBackupExtension is added to the path of a backup file when the
+// backup is compressed.
+const CompressedBackupExtension = ".gz"
+
+// gzipHeader begins every gzip stream. A serialized state can never begin
+// with these bytes, so compressed files can be told apart from plain ones.
+var gzipHeader = []byte{0x1f, 0x8b}
+
+// CompressBackupsFromEnv returns whether state backups should be compressed,
+// as configured by StateBackupCompressEnvVar. Backups are not compressed if
+// the variable is not set.
+func CompressBackupsFromEnv() (bool, error) {
+	v := os.Getenv(StateBackupCompressEnvVar)
+	if v == "" {
+		return false, nil
+	}
+
+	compress, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: must be true or false", StateBackupCompressEnvVar, v)
+	}
+	return compress, nil
+}
+
+func compressState(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressState returns data decompressed if it is compressed, or else
+// unchanged.
+func decompressState(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipHeader) {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress state: %s", err)
+	}
+	defer r.Close()
+
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress state: %s", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCompressBackupsFromEnv(t *testing.T) {
+	defer os.Setenv(StateBackupCompressEnvVar, os.Getenv(StateBackupCompressEnvVar))
+
+	cases := map[string]bool{
+		"":      false,
+		"0":     false,
+		"false": false,
+		"1":     true,
+		"true":  true,
+	}
+	for v, want := range cases {
+		os.Setenv(StateBackupCompressEnvVar, v)
+		got, err := CompressBackupsFromEnv()
+		if err != nil {
+			t.Fatalf("%q: err: %s", v, err)
+		}
+		if got != want {
+			t.Fatalf("%q: got %t, want %t", v, got, want)
+		}
+	}
+
+	os.Setenv(StateBackupCompressEnvVar, "yes please")
+	if _, err := CompressBackupsFromEnv(); err == nil || !strings.Contains(err.Error(), StateBackupCompressEnvVar) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestDecompressState(t *testing.T) {
+	plain := []byte(`{"version": 3}`)
+	compressed, err := compressState(plain)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.HasPrefix(compressed, gzipHeader) {
+		t.Fatalf("compressed state has no header: %q", compressed)
+	}
+
+	for _, data := range [][]byte{plain, compressed} {
+		got, err := decompressState(data)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("bad: %q", got)
+		}
+	}
+
+	if _, err := decompressState(compressed[:len(compressed)-4]); err == nil {
+		t.Fatal("decompressed a truncated state")
+	}
+}
+
+func TestLocalState_compressed(t *testing.T) {
+	ls := testLocalState(t)
+	defer os.Remove(ls.Path)
+	ls.Compress = true
+	TestState(t, ls)
+
+	raw, err := ioutil.ReadFile(ls.Path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.HasPrefix(raw, gzipHeader) {
+		t.Fatalf("state file is not compressed: %q", raw)
+	}
+
+	// A reader that doesn't compress still reads a compressed state.
+	other := &LocalState{Path: ls.Path}
+	if err := other.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !other.State().Equal(ls.State()) {
+		t.Fatalf("bad: %s", other.State())
+	}
+}
@@ -0,0 +1,124 @@
+package state
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StateEncryptionKeyEnvVar is the name of the environment variable that
+// EncryptionFromEnv reads the state encryption key from. Its value must be
+// a standard base64 encoding of a 16, 24 or 32 byte AES key.
+const StateEncryptionKeyEnvVar = "TF_STATE_ENCRYPTION_KEY"
+
+// encryptedStateHeader begins every state file written by the AES-GCM
+// implementation of StateEncryption, so that encrypted files can be told
+// apart from plaintext ones.
+var encryptedStateHeader = []byte("TFSTATE-ENCRYPTED-AES-GCM-V1\n")
+
+// StateEncryption transforms serialized state as it is written to and read
+// from local files, allowing the state to be encrypted at rest.
+//
+// Decrypt must accept anything that Encrypt returns. Implementations may
+// also accept other input, such as plaintext written before encryption was
+// configured.
+type StateEncryption interface {
+	Encrypt([]byte) ([]byte, error)
+	Decrypt([]byte) ([]byte, error)
+}
+
+// PassthroughEncryption is the StateEncryption used when encryption is not
+// configured. It leaves state unchanged, except that it refuses to read a
+// state that was encrypted, since decoding it as plaintext would fail with
+// a confusing error.
+type PassthroughEncryption struct{}
+
+func (PassthroughEncryption) Encrypt(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (PassthroughEncryption) Decrypt(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, encryptedStateHeader) {
+		return nil, fmt.Errorf(
+			"state is encrypted, but no encryption key is configured; set %s to read it",
+			StateEncryptionKeyEnvVar)
+	}
+	return data, nil
+}
+
+// NewAESGCMEncryption returns a StateEncryption that encrypts state with
+// AES-GCM using the given key, which must be 16, 24 or 32 bytes long.
+//
+// A state that does not begin with the encrypted state header is assumed to
+// be plaintext written before encryption was configured, and is returned
+// unchanged by Decrypt so that it will be encrypted on the next write.
+func NewAESGCMEncryption(key []byte) (StateEncryption, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCMEncryption{aead: aead}, nil
+}
+
+// EncryptionFromEnv returns the StateEncryption configured by the
+// environment, which is AES-GCM keyed from StateEncryptionKeyEnvVar if it
+// is set, or PassthroughEncryption otherwise.
+func EncryptionFromEnv() (StateEncryption, error) {
+	encoded := os.Getenv(StateEncryptionKeyEnvVar)
+	if encoded == "" {
+		return PassthroughEncryption{}, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", StateEncryptionKeyEnvVar, err)
+	}
+	enc, err := NewAESGCMEncryption(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", StateEncryptionKeyEnvVar, err)
+	}
+	return enc, nil
+}
+
+type aesGCMEncryption struct {
+	aead cipher.AEAD
+}
+
+func (e *aesGCMEncryption) Encrypt(data []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedStateHeader)+len(nonce)+len(data)+e.aead.Overhead())
+	out = append(out, encryptedStateHeader...)
+	out = append(out, nonce...)
+	return e.aead.Seal(out, nonce, data, encryptedStateHeader), nil
+}
+
+func (e *aesGCMEncryption) Decrypt(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedStateHeader) {
+		return data, nil
+	}
+
+	data = data[len(encryptedStateHeader):]
+	if len(data) < e.aead.NonceSize() {
+		return nil, errors.New("encrypted state is truncated")
+	}
+	nonce, ciphertext := data[:e.aead.NonceSize()], data[e.aead.NonceSize():]
+	plaintext, err := e.aead.Open(nil, nonce, ciphertext, encryptedStateHeader)
+	if err != nil {
+		return nil, errors.New("failed to decrypt state; the encryption key may be wrong")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAESGCMEncryption(t *testing.T) {
+	enc, err := NewAESGCMEncryption(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	plaintext := []byte(`{"secret": "SECRET"}`)
+	ciphertext, err := enc.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes.Contains(ciphertext, []byte("SECRET")) {
+		t.Fatalf("encrypted state contains plaintext: %q", ciphertext)
+	}
+	if !bytes.HasPrefix(ciphertext, encryptedStateHeader) {
+		t.Fatalf("encrypted state has no header: %q", ciphertext)
+	}
+
+	got, err := enc.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("bad: %q", got)
+	}
+
+	// Plaintext written before encryption was configured is still readable.
+	got, err = enc.Decrypt(plaintext)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("bad: %q", got)
+	}
+
+	other, err := NewAESGCMEncryption(bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := other.Decrypt(ciphertext); err == nil {
+		t.Fatal("decrypted with the wrong key")
+	}
+
+	if _, err := (PassthroughEncryption{}).Decrypt(ciphertext); err == nil {
+		t.Fatal("passthrough accepted an encrypted state")
+	}
+}
+
+func TestNewAESGCMEncryption_badKey(t *testing.T) {
+	if _, err := NewAESGCMEncryption([]byte("short")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestEncryptionFromEnv(t *testing.T) {
+	defer os.Setenv(StateEncryptionKeyEnvVar, os.Getenv(StateEncryptionKeyEnvVar))
+
+	os.Setenv(StateEncryptionKeyEnvVar, "")
+	enc, err := EncryptionFromEnv()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := enc.(PassthroughEncryption); !ok {
+		t.Fatalf("bad: %#v", enc)
+	}
+
+	os.Setenv(StateEncryptionKeyEnvVar, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16)))
+	enc, err = EncryptionFromEnv()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := enc.(*aesGCMEncryption); !ok {
+		t.Fatalf("bad: %#v", enc)
+	}
+
+	os.Setenv(StateEncryptionKeyEnvVar, "not base64!")
+	if _, err := EncryptionFromEnv(); err == nil || !strings.Contains(err.Error(), StateEncryptionKeyEnvVar) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestLocalState_encrypted(t *testing.T) {
+	enc, err := NewAESGCMEncryption(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ls := testLocalState(t)
+	defer os.Remove(ls.Path)
+	ls.Encryption = enc
+	TestState(t, ls)
+
+	raw, err := ioutil.ReadFile(ls.Path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.HasPrefix(raw, encryptedStateHeader) {
+		t.Fatalf("state file is not encrypted: %q", raw)
+	}
+
+	// A fresh reader with the same key sees the same state.
+	other := &LocalState{Path: ls.Path, Encryption: enc}
+	if err := other.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !other.State().Equal(ls.State()) {
+		t.Fatalf("bad: %s", other.State())
+	}
+}
@@ -25,6 +25,15 @@ type LocalState struct {
 	Path    string
 	PathOut string
 
+	// Encryption, if set, is used to encrypt the state as it is written and
+	// decrypt it as it is read. If nil, PassthroughEncryption is used.
+	Encryption StateEncryption
+
+	// Compress, if set, causes the state to be compressed with gzip as it
+	// is written. A compressed state is always decompressed as it is read,
+	// whether or not this is set.
+	Compress bool
+
 	// the file handle corresponding to PathOut
 	stateFileOut *os.File
 
@@ -99,7 +108,24 @@ func (s *LocalState) WriteState(state *terraform.State) error {
 		s.state.Serial++
 	}
 
-	if err := terraform.WriteState(s.state, s.stateFileOut); err != nil {
+	var buf bytes.Buffer
+	if err := terraform.WriteState(s.state, &buf); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if s.Compress {
+		// Encrypted data doesn't compress, so this must come first.
+		compressed, err := compressState(data)
+		if err != nil {
+			return fmt.Errorf("failed to compress state: %s", err)
+		}
+		data = compressed
+	}
+	data, err := s.encryption().Encrypt(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt state: %s", err)
+	}
+	if _, err := s.stateFileOut.Write(data); err != nil {
 		return err
 	}
 
@@ -148,7 +174,20 @@ func (s *LocalState) RefreshState() error {
 		reader = s.stateFileOut
 	}
 
-	state, err := terraform.ReadState(reader)
+	raw, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	raw, err = s.encryption().Decrypt(raw)
+	if err != nil {
+		return err
+	}
+	raw, err = decompressState(raw)
+	if err != nil {
+		return err
+	}
+
+	state, err := terraform.ReadState(bytes.NewReader(raw))
 	// if there's no state we just assign the nil return value
 	if err != nil && err != terraform.ErrNoState {
 		return err
@@ -159,6 +198,13 @@ func (s *LocalState) RefreshState() error {
 	return nil
 }
 
+func (s *LocalState) encryption() StateEncryption {
+	if s.Encryption == nil {
+		return PassthroughEncryption{}
+	}
+	return s.Encryption
+}
+
 // Lock implements a local filesystem state.Locker.
 func (s *LocalState) Lock(info *LockInfo) (string, error) {
 	s.mu.Lock()
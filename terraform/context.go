@@ -620,6 +620,25 @@ func (c *Context) Plan() (*Plan, error) {
 func (c *Context) Refresh() (*State, error) {
 	defer c.acquireRun("refresh")()
 
+	return c.refresh()
+}
+
+// RefreshTargets is like Refresh, but only refreshes the resources with the
+// given addresses and the resources that they depend on, in place of any
+// targets that the context was created with.
+func (c *Context) RefreshTargets(targets []string) (*State, error) {
+	defer c.acquireRun("refresh")()
+
+	old := c.targets
+	c.targets = targets
+	defer func() {
+		c.targets = old
+	}()
+
+	return c.refresh()
+}
+
+func (c *Context) refresh() (*State, error) {
 	// Copy our own state
 	c.state = c.state.DeepCopy()
 
@@ -112,6 +112,33 @@ func (d *Diff) Empty() bool {
 	return true
 }
 
+// ResourceAddrs returns the sorted addresses of the resource instances that
+// have changes in the diff, in the form accepted for ContextOpts.Targets.
+func (d *Diff) ResourceAddrs() ([]string, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	var addrs []string
+	for _, m := range d.Modules {
+		for key, rd := range m.Resources {
+			if rd.Empty() {
+				continue
+			}
+
+			addr, err := parseResourceAddressInternal(key)
+			if err != nil {
+				return nil, err
+			}
+			addr.Path = normalizeModulePath(m.Path)[1:]
+			addrs = append(addrs, addr.String())
+		}
+	}
+
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
 // Equal compares two diffs for exact equality.
 //
 // This is different from the Same comparison that is supported which
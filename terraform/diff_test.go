@@ -33,6 +33,46 @@ func TestDiffEmpty(t *testing.T) {
 	}
 }
 
+func TestDiffResourceAddrs(t *testing.T) {
+	var diff *Diff
+	if addrs, err := diff.ResourceAddrs(); err != nil || addrs != nil {
+		t.Fatalf("bad: %#v, %s", addrs, err)
+	}
+
+	changed := &InstanceDiff{
+		Attributes: map[string]*ResourceAttrDiff{
+			"foo": &ResourceAttrDiff{
+				Old: "foo",
+				New: "bar",
+			},
+		},
+	}
+
+	diff = new(Diff)
+	root := diff.AddModule(rootModulePath)
+	root.Resources["aws_instance.foo"] = changed
+	root.Resources["aws_instance.bar.1"] = &InstanceDiff{Destroy: true}
+	root.Resources["aws_instance.unchanged"] = &InstanceDiff{}
+	root.Resources["data.aws_ami.baz"] = changed
+	child := diff.AddModule([]string{"root", "child"})
+	child.Resources["aws_instance.foo"] = changed
+
+	addrs, err := diff.ResourceAddrs()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"aws_instance.bar[1]",
+		"aws_instance.foo",
+		"data.aws_ami.baz",
+		"module.child.aws_instance.foo",
+	}
+	if !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("expected: %#v, got: %#v", expected, addrs)
+	}
+}
+
 func TestDiffEmpty_taintedIsNotEmpty(t *testing.T) {
 	diff := new(Diff)
 
@@ -1,6 +1,8 @@
 package terraform
 
 import (
+	"bytes"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -88,3 +90,35 @@ root
   provider.openstack (close)
 var.foo
 `
+
+func BenchmarkGraphBuildLargeConfig(b *testing.B) {
+	mod := testModuleInline(b, map[string]string{
+		"main.tf": testLargeGraphConfig(1000, 1000),
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		builder := &PlanGraphBuilder{
+			Module:    mod,
+			Providers: []string{"aws"},
+		}
+		if _, err := builder.Build(RootModulePath); err != nil {
+			b.Fatalf("err: %s", err)
+		}
+	}
+}
+
+// testLargeGraphConfig returns a configuration with a chain of resources
+// that each depend on the one before, and a resource that many others
+// depend on.
+func testLargeGraphConfig(chain, fanout int) string {
+	var buf bytes.Buffer
+	buf.WriteString("resource \"aws_instance\" \"chain0\" {}\n")
+	for i := 1; i < chain; i++ {
+		fmt.Fprintf(&buf, "resource \"aws_instance\" \"chain%d\" { foo = \"${aws_instance.chain%d.id}\" }\n", i, i-1)
+	}
+	buf.WriteString("resource \"aws_instance\" \"hub\" {}\n")
+	for i := 0; i < fanout; i++ {
+		fmt.Fprintf(&buf, "resource \"aws_instance\" \"spoke%d\" { foo = \"${aws_instance.hub.id}\" }\n", i)
+	}
+	return buf.String()
+}
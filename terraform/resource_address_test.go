@@ -1258,6 +1258,21 @@ func TestResourceAddressLess(t *testing.T) {
 			"a.b[10]",
 			true,
 		},
+		{
+			"a.b[1]",
+			"a.c[0]",
+			true,
+		},
+		{
+			"a.c",
+			"b.a",
+			true,
+		},
+		{
+			"data.b.b",
+			"a.a",
+			true,
+		},
 		{
 			"a.b",
 			"a.b.deposed",
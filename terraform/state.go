@@ -7,8 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
+	"os"
 	"reflect"
 	"sort"
 	"strconv"
@@ -552,16 +552,18 @@ func (s *State) MarshalEqual(other *State) bool {
 		return false
 	}
 
-	recvBuf := &bytes.Buffer{}
-	otherBuf := &bytes.Buffer{}
+	recvBuf := getStateBuffer()
+	defer putStateBuffer(recvBuf)
+	otherBuf := getStateBuffer()
+	defer putStateBuffer(otherBuf)
 
-	err := WriteState(s, recvBuf)
+	err := encodeState(s, recvBuf)
 	if err != nil {
 		// should never happen, since we're writing to a buffer
 		panic(err)
 	}
 
-	err = WriteState(other, otherBuf)
+	err = encodeState(other, otherBuf)
 	if err != nil {
 		// should never happen, since we're writing to a buffer
 		panic(err)
@@ -1799,6 +1801,30 @@ type jsonStateVersionIdentifier struct {
 	Version int `json:"version"`
 }
 
+// sniffStateVersion looks for the version at the start of a JSON state, which
+// is where WriteState puts it, so that we don't need to decode the whole state
+// an extra time just to find out how to decode it. If the version isn't the
+// first key in the object the result is false, and the caller must decode the
+// whole state to find it.
+func sniffStateVersion(jsonBytes []byte) (*jsonStateVersionIdentifier, bool) {
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+	if tok, err := dec.Token(); err != nil || tok != "version" {
+		return nil, false
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, false
+	}
+	v, ok := tok.(float64)
+	if !ok || v != float64(int(v)) {
+		return nil, false
+	}
+	return &jsonStateVersionIdentifier{Version: int(v)}, true
+}
+
 // Check if this is a V0 format - the magic bytes at the start of the file
 // should be "tfstate" if so. We no longer support upgrading this type of
 // state but return an error message explaining to a user how they can
@@ -1821,6 +1847,24 @@ func testForV0State(buf *bufio.Reader) error {
 // ErrNoState is returned by ReadState when the io.Reader contains no data
 var ErrNoState = errors.New("no state")
 
+// stateSizeHint returns the number of bytes that are expected to be read
+// from src, or zero if that isn't known.
+func stateSizeHint(src io.Reader) int {
+	switch r := src.(type) {
+	case interface {
+		Stat() (os.FileInfo, error)
+	}:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return int(info.Size())
+		}
+	case interface {
+		Len() int
+	}:
+		return r.Len()
+	}
+	return 0
+}
+
 // ReadState reads a state structure out of a reader in the format that
 // was written by WriteState.
 func ReadState(src io.Reader) (*State, error) {
@@ -1836,15 +1880,21 @@ func ReadState(src io.Reader) (*State, error) {
 	}
 
 	// If we are JSON we buffer the whole thing in memory so we can read it twice.
-	// This is suboptimal, but will work for now.
-	jsonBytes, err := ioutil.ReadAll(buf)
-	if err != nil {
+	// This is suboptimal, but will work for now. When the size of the source
+	// is known we allocate the whole buffer up front, since growing it a
+	// step at a time dominates the cost of reading a large state.
+	jsonBuf := bytes.NewBuffer(make([]byte, 0, stateSizeHint(src)+bytes.MinRead))
+	if _, err := jsonBuf.ReadFrom(buf); err != nil {
 		return nil, fmt.Errorf("Reading state file failed: %v", err)
 	}
+	jsonBytes := jsonBuf.Bytes()
 
-	versionIdentifier := &jsonStateVersionIdentifier{}
-	if err := json.Unmarshal(jsonBytes, versionIdentifier); err != nil {
-		return nil, fmt.Errorf("Decoding state file version failed: %v", err)
+	versionIdentifier, ok := sniffStateVersion(jsonBytes)
+	if !ok {
+		versionIdentifier = &jsonStateVersionIdentifier{}
+		if err := json.Unmarshal(jsonBytes, versionIdentifier); err != nil {
+			return nil, fmt.Errorf("Decoding state file version failed: %v", err)
+		}
 	}
 
 	var result *State
@@ -1995,9 +2045,9 @@ func ReadStateV3(jsonBytes []byte) (*State, error) {
 	// Now we write the state back out to detect any changes in normaliztion.
 	// If our state is now written out differently, bump the serial number to
 	// prevent conflicts.
-	var buf bytes.Buffer
-	err := WriteState(state, &buf)
-	if err != nil {
+	buf := getStateBuffer()
+	defer putStateBuffer(buf)
+	if err := encodeState(state, buf); err != nil {
 		return nil, err
 	}
 
@@ -2016,6 +2066,23 @@ func WriteState(d *State, dst io.Writer) error {
 		return nil
 	}
 
+	buf := getStateBuffer()
+	defer putStateBuffer(buf)
+	if err := encodeState(d, buf); err != nil {
+		return err
+	}
+
+	// Write the data out to the dst
+	if _, err := dst.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("Failed to write state: %v", err)
+	}
+
+	return nil
+}
+
+// encodeState normalizes the given state and appends it to buf in the
+// format that WriteState writes.
+func encodeState(d *State, buf *bytes.Buffer) error {
 	// make sure we have no uninitialized fields
 	d.init()
 
@@ -2038,23 +2105,65 @@ func WriteState(d *State, dst io.Writer) error {
 		}
 	}
 
-	// Encode the data in a human-friendly way
-	data, err := json.MarshalIndent(d, "", "    ")
-	if err != nil {
+	// Encode the data in a human-friendly way. The encoder terminates the
+	// data with a newline, which MarshalIndent doesn't, so this saves
+	// copying the whole encoded state again to append one. An encoder set
+	// to indent its output keeps a buffer of its own for that, which we
+	// could neither reuse nor clear, so the data is indented separately.
+	compact := getStateBuffer()
+	defer putStateBuffer(compact)
+	if err := json.NewEncoder(compact).Encode(d); err != nil {
 		return fmt.Errorf("Failed to encode state: %s", err)
 	}
-
-	// We append a newline to the data because MarshalIndent doesn't
-	data = append(data, '\n')
-
-	// Write the data out to the dst
-	if _, err := io.Copy(dst, bytes.NewReader(data)); err != nil {
-		return fmt.Errorf("Failed to write state: %v", err)
+	if err := json.Indent(buf, compact.Bytes(), "", "    "); err != nil {
+		return fmt.Errorf("Failed to encode state: %s", err)
 	}
 
 	return nil
 }
 
+// maxPooledStateBuffer is the capacity above which a buffer is not returned
+// to stateBufferPool, so that one unusually large state doesn't keep its
+// memory alive for the rest of the process.
+const maxPooledStateBuffer = 64 << 20
+
+// stateBufferPool holds the buffers that states are encoded into, so that
+// writing a state many times, as apply does, doesn't allocate and grow a
+// new buffer each time.
+var stateBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getStateBuffer returns an empty buffer from stateBufferPool. It must be
+// given back with putStateBuffer once nothing refers to its contents.
+func getStateBuffer() *bytes.Buffer {
+	buf := stateBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putStateBuffer returns a buffer to stateBufferPool.
+//
+// States often contain secrets, so the whole of the buffer's memory is
+// zeroed first. Reset alone would leave the old contents in place beyond
+// the buffer's length, where they would be kept in memory by the pool
+// and could be exposed by a later misuse of the buffer.
+func putStateBuffer(buf *bytes.Buffer) {
+	data := buf.Bytes()
+	data = data[:cap(data)]
+	for i := range data {
+		data[i] = 0
+	}
+	buf.Reset()
+
+	if buf.Cap() > maxPooledStateBuffer {
+		return
+	}
+	stateBufferPool.Put(buf)
+}
+
 // resourceNameSort implements the sort.Interface to sort name parts lexically for
 // strings and numerically for integer indexes.
 type resourceNameSort []string
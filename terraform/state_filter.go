@@ -59,6 +59,54 @@ func (f *StateFilter) Filter(fs ...string) ([]*StateFilterResult, error) {
 	return results, nil
 }
 
+// Addresses returns the addresses of all of the resource instances in the
+// state, in the same order as the instance results from calling Filter
+// with no arguments.
+//
+// This is much cheaper than filtering the whole state when only the
+// addresses are needed, since it doesn't build a result for every module
+// and resource, and it parses each address only once to sort them.
+func (f *StateFilter) Addresses() []string {
+	var keys []stateFilterSortKey
+	for _, m := range f.State.Modules {
+		for n, r := range m.Resources {
+			key, err := ParseResourceStateKey(n)
+			if err != nil {
+				// Filter ignores these too.
+				continue
+			}
+
+			// Only primary instances are listed, since Filter doesn't
+			// return deposed ones.
+			if r.Primary == nil {
+				continue
+			}
+
+			keys = append(keys, newStateFilterSortKey(&ResourceAddress{
+				Path:  m.Path[1:],
+				Name:  key.Name,
+				Type:  key.Type,
+				Index: key.Index,
+			}))
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+
+	// Filter strips duplicate addresses, which are next to each other
+	// once sorted.
+	addrs := make([]string, 0, len(keys))
+	for i, k := range keys {
+		if i > 0 && k.Address == keys[i-1].Address {
+			continue
+		}
+		addrs = append(addrs, k.Address)
+	}
+	return addrs
+}
+
 func (f *StateFilter) filterSingle(a *ResourceAddress) []*StateFilterResult {
 	// The slice to keep track of results
 	var results []*StateFilterResult
@@ -251,17 +299,55 @@ func (s StateFilterResultSlice) Less(i, j int) bool {
 	a, b := s[i], s[j]
 
 	// if these address contain an index, we want to sort by index rather than name
-	addrA, errA := ParseResourceAddress(a.Address)
-	addrB, errB := ParseResourceAddress(b.Address)
-	if errA == nil && errB == nil && addrA.Name == addrB.Name && addrA.Index != addrB.Index {
-		return addrA.Index < addrB.Index
-	}
-
-	// If the addresses are different it is just lexographic sorting
-	if a.Address != b.Address {
-		return a.Address < b.Address
+	keyA, keyB := parseStateFilterSortKey(a.Address), parseStateFilterSortKey(b.Address)
+	if keyA != keyB {
+		return keyA.less(keyB)
 	}
 
 	// Addresses are the same, which means it matters on the type
 	return a.sortedType() < b.sortedType()
 }
+
+// stateFilterSortKey is the part of a result that the results are sorted
+// by. Instances of the same resource are sorted by index, rather than
+// lexically, so that foo[2] comes before foo[10].
+//
+// Comparing the whole key, rather than just the resource names, keeps the
+// order consistent when resources of different types share a name, so the
+// order of the results doesn't depend on the order they were found in.
+type stateFilterSortKey struct {
+	// Resource is the address without its index or instance type.
+	Resource string
+	Index    int
+	Address  string
+}
+
+func newStateFilterSortKey(addr *ResourceAddress) stateFilterSortKey {
+	resource := *addr
+	resource.Index = -1
+	resource.InstanceTypeSet = false
+	return stateFilterSortKey{
+		Resource: resource.String(),
+		Index:    addr.Index,
+		Address:  addr.String(),
+	}
+}
+
+func parseStateFilterSortKey(v string) stateFilterSortKey {
+	addr, err := ParseResourceAddress(v)
+	if err != nil {
+		// If we can't parse it then it is just lexographic sorting
+		return stateFilterSortKey{Resource: v, Index: -1, Address: v}
+	}
+	return newStateFilterSortKey(addr)
+}
+
+func (k stateFilterSortKey) less(other stateFilterSortKey) bool {
+	if k.Resource != other.Resource {
+		return k.Resource < other.Resource
+	}
+	if k.Index != other.Index {
+		return k.Index < other.Index
+	}
+	return k.Address < other.Address
+}
@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -162,3 +163,167 @@ func TestStateFilterFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestStateFilterFilter_order(t *testing.T) {
+	// Resources of different types that share a name used to be sorted by
+	// index against each other, which made the order depend on the order
+	// that the results were found in.
+	state := testStateFilterMixed()
+	want := []string{
+		"aws_eip.web[0]",
+		"aws_eip.web[1]",
+		"aws_eip.web[2]",
+		"aws_instance.web",
+		"aws_instance.web[1]",
+		"aws_instance.web[2]",
+		"aws_instance.web[10]",
+		"aws_instance.web-b",
+		"aws_instance.web2[0]",
+		"aws_instance.web2[1]",
+		"module.child.aws_instance.web[0]",
+		"module.child.aws_instance.web[1]",
+	}
+
+	for i := 0; i < 20; i++ {
+		results, err := (&StateFilter{State: state}).Filter()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		var got []string
+		for _, r := range results {
+			if _, ok := r.Value.(*InstanceState); ok {
+				got = append(got, r.Address)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("wrong order\ngot:  %#v\nwant: %#v", got, want)
+		}
+	}
+}
+
+func TestStateFilterAddresses(t *testing.T) {
+	states := map[string]*State{
+		"mixed": testStateFilterMixed(),
+		"large": testStateFilterLarge(200),
+	}
+	fixtures, err := filepath.Glob(filepath.Join("./test-fixtures", "state-filter", "*.tfstate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range fixtures {
+		f, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, err := ReadState(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", fn, err)
+		}
+		states[filepath.Base(fn)] = state
+	}
+
+	for name, state := range states {
+		t.Run(name, func(t *testing.T) {
+			filter := &StateFilter{State: state}
+			results, err := filter.Filter()
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			want := []string{}
+			for _, r := range results {
+				if _, ok := r.Value.(*InstanceState); ok {
+					want = append(want, r.Address)
+				}
+			}
+
+			got := filter.Addresses()
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("addresses don't match Filter\ngot:  %#v\nwant: %#v", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkStateFilterFilter(b *testing.B) {
+	filter := &StateFilter{State: testStateFilterLarge(1000)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := filter.Filter(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStateFilterAddresses(b *testing.B) {
+	filter := &StateFilter{State: testStateFilterLarge(1000)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Addresses()
+	}
+}
+
+// testStateFilterMixed returns a state with resources whose names and
+// indexes are easy to sort in the wrong order.
+func testStateFilterMixed() *State {
+	state := NewState()
+	root := state.RootModule()
+	child := state.AddModule([]string{"root", "child"})
+	resources := map[*ModuleState][]string{
+		root: {
+			"aws_eip.web.0",
+			"aws_eip.web.1",
+			"aws_eip.web.2",
+			"aws_instance.web",
+			"aws_instance.web.1",
+			"aws_instance.web.2",
+			"aws_instance.web.10",
+			"aws_instance.web-b",
+			"aws_instance.web2.0",
+			"aws_instance.web2.1",
+		},
+		child: {
+			"aws_instance.web.0",
+			"aws_instance.web.1",
+			"aws_instance.deposed",
+		},
+	}
+	for mod, keys := range resources {
+		for _, k := range keys {
+			r := &ResourceState{Primary: &InstanceState{ID: k}}
+			if k == "aws_instance.deposed" {
+				r = &ResourceState{Deposed: []*InstanceState{{ID: k}}}
+			}
+			mod.Resources[k] = r
+		}
+	}
+	return state
+}
+
+// testStateFilterLarge returns a state with n instances of each of two
+// counted resources, in the root module and in each of two child modules.
+func testStateFilterLarge(n int) *State {
+	state := NewState()
+	for _, name := range []string{"", "a", "b"} {
+		mod := state.RootModule()
+		if name != "" {
+			mod = state.AddModule([]string{"root", name})
+		}
+		for i := 0; i < n; i++ {
+			for _, typ := range []string{"aws_instance", "aws_eip"} {
+				id := fmt.Sprintf("%s-%d", typ, i)
+				mod.Resources[fmt.Sprintf("%s.foo.%d", typ, i)] = &ResourceState{
+					Type: typ,
+					Primary: &InstanceState{
+						ID:         id,
+						Attributes: map[string]string{"id": id},
+					},
+				}
+			}
+		}
+	}
+	return state
+}
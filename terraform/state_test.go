@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"sort"
@@ -1645,6 +1646,47 @@ func TestReadStateNewVersion(t *testing.T) {
 	}
 }
 
+func TestReadStateVersionNotFirst(t *testing.T) {
+	// WriteState always puts the version first, but a state that was edited
+	// by hand might not.
+	src := `{"serial": 2, "lineage": "abc", "version": 3}`
+
+	s, err := ReadState(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if s.Version != 3 || s.Serial < 2 || s.Lineage != "abc" {
+		t.Fatalf("wrong state: %#v", s)
+	}
+}
+
+func TestSniffStateVersion(t *testing.T) {
+	cases := map[string]struct {
+		Version int
+		OK      bool
+	}{
+		`{"version": 3, "serial": 1}`: {3, true},
+		`{ "version" : 1 }`:           {1, true},
+		`{"serial": 1, "version": 3}`: {0, false},
+		`{"version": "3"}`:            {0, false},
+		`{"version": 3.5}`:            {0, false},
+		`["version", 3]`:              {0, false},
+		`{"version": 3, "serial": 1`:  {3, true},
+		`not json`:                    {0, false},
+	}
+
+	for src, want := range cases {
+		got, ok := sniffStateVersion([]byte(src))
+		if ok != want.OK {
+			t.Errorf("%s: ok is %t; want %t", src, ok, want.OK)
+			continue
+		}
+		if ok && got.Version != want.Version {
+			t.Errorf("%s: version is %d; want %d", src, got.Version, want.Version)
+		}
+	}
+}
+
 func TestReadStateEmptyOrNilFile(t *testing.T) {
 	var emptyState bytes.Buffer
 	_, err := ReadState(&emptyState)
@@ -1754,6 +1796,91 @@ func TestWriteStateTFVersion(t *testing.T) {
 	}
 }
 
+func TestWriteState_reuseBuffer(t *testing.T) {
+	const secret = "s3cr3t-value"
+
+	small := &State{Lineage: "small"}
+	var want bytes.Buffer
+	if err := WriteState(small, &want); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Writing a larger state first leaves more in the pooled buffer than
+	// the small state needs, so none of it must show up in the output.
+	large := testStateWithResources(100, secret)
+	if err := WriteState(large, ioutil.Discard); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var got bytes.Buffer
+	if err := WriteState(small, &got); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Fatalf("wrong output\ngot:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+	if strings.Contains(got.String(), secret) {
+		t.Fatalf("output contains data from an earlier write:\n%s", got.String())
+	}
+}
+
+func TestPutStateBuffer(t *testing.T) {
+	buf := getStateBuffer()
+	buf.WriteString("s3cr3t-value")
+	data := buf.Bytes()
+	data = data[:cap(data)]
+	putStateBuffer(buf)
+
+	for i, b := range data {
+		if b != 0 {
+			t.Fatalf("byte %d is %q after the buffer was returned to the pool", i, b)
+		}
+	}
+
+	if buf := getStateBuffer(); buf.Len() != 0 {
+		t.Fatalf("buffer from pool is not empty: %q", buf.String())
+	}
+}
+
+func BenchmarkWriteState(b *testing.B) {
+	state := testStateWithResources(1000, "value")
+
+	var buf bytes.Buffer
+	if err := WriteState(state, &buf); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := WriteState(state, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// testStateWithResources returns a state with n resources in the root
+// module, each with an attribute set to the given value.
+func testStateWithResources(n int, value string) *State {
+	state := NewState()
+	mod := state.RootModule()
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("id-%d", i)
+		mod.Resources[fmt.Sprintf("test_resource.foo.%d", i)] = &ResourceState{
+			Type: "test_resource",
+			Primary: &InstanceState{
+				ID: id,
+				Attributes: map[string]string{
+					"id":       id,
+					"password": value,
+				},
+			},
+		}
+	}
+	return state
+}
+
 func TestParseResourceStateKey(t *testing.T) {
 	cases := []struct {
 		Input       string
@@ -99,7 +99,7 @@ func testModule(t *testing.T, name string) *module.Tree {
 
 // testModuleInline takes a map of path -> config strings and yields a config
 // structure with those files loaded from disk
-func testModuleInline(t *testing.T, config map[string]string) *module.Tree {
+func testModuleInline(t testing.TB, config map[string]string) *module.Tree {
 	cfgPath, err := ioutil.TempDir("", "tf-test")
 	if err != nil {
 		t.Errorf("Error creating temporary directory for config: %s", err)